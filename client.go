@@ -0,0 +1,203 @@
+package nextcloudgo
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Option configures the HTTP client of a NextcloudGo created with New
+type Option func(*clientConfig) error
+
+type clientConfig struct {
+	client       *http.Client
+	transport    http.RoundTripper
+	timeout      time.Duration
+	proxy        func(*http.Request) (*url.URL, error)
+	certPath     string
+	certificates []tls.Certificate
+	version      *Version
+}
+
+// New returns a NextcloudGo for the given server and login.
+// The HTTP client, including the TLS configuration, is built once and reused
+// by every request, so connections are pooled between calls.
+// Returns ErrInvalidCertificate when the CA bundle does not contain a certificate.
+func New(serverURL, user, password string, options ...Option) (NextcloudGo, error) {
+	config := clientConfig{}
+	for _, option := range options {
+		if err := option(&config); err != nil {
+			return NextcloudGo{}, err
+		}
+	}
+
+	client, err := config.build()
+	if err != nil {
+		return NextcloudGo{}, err
+	}
+
+	return NextcloudGo{
+		ServerURL: strings.TrimSuffix(serverURL, "/"),
+		CertPath:  config.certPath,
+		User:      user,
+		Password:  password,
+		client:    client,
+		info:      &serverInfo{version: config.version, fixed: config.version != nil},
+	}, nil
+}
+
+// WithHTTPClient uses the given client for all requests instead of creating one.
+// The client is copied, so setting a timeout does not modify the caller's client.
+func WithHTTPClient(client *http.Client) Option {
+	return func(config *clientConfig) error {
+		if client == nil {
+			return errors.New("HTTP client must not be nil")
+		}
+		config.client = client
+		return nil
+	}
+}
+
+// WithTransport uses the given RoundTripper to perform the requests.
+// TLS and proxy options can only be combined with an *http.Transport.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(config *clientConfig) error {
+		if transport == nil {
+			return errors.New("Transport must not be nil")
+		}
+		config.transport = transport
+		return nil
+	}
+}
+
+// WithTimeout limits the time a single request may take, including reading the response body
+func WithTimeout(timeout time.Duration) Option {
+	return func(config *clientConfig) error {
+		config.timeout = timeout
+		return nil
+	}
+}
+
+// WithProxy sets the function selecting the proxy for each request, see http.Transport.Proxy
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) Option {
+	return func(config *clientConfig) error {
+		config.proxy = proxy
+		return nil
+	}
+}
+
+// WithProxyURL sends all requests through the given proxy
+func WithProxyURL(proxyURL string) Option {
+	return func(config *clientConfig) error {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return err
+		}
+		config.proxy = http.ProxyURL(u)
+		return nil
+	}
+}
+
+// WithServerVersion skips asking the server for its version when checking for features
+func WithServerVersion(version string) Option {
+	return func(config *clientConfig) error {
+		v, err := ParseVersion(version)
+		if err != nil {
+			return err
+		}
+		config.version = &v
+		return nil
+	}
+}
+
+// WithCACertFile trusts the certificates of the given PEM bundle instead of the system pool
+func WithCACertFile(path string) Option {
+	return func(config *clientConfig) error {
+		config.certPath = path
+		return nil
+	}
+}
+
+// WithClientCertificate authenticates against the server with the given
+// PEM encoded certificate and key (mutual TLS)
+func WithClientCertificate(certFile, keyFile string) Option {
+	return func(config *clientConfig) error {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+		config.certificates = append(config.certificates, cert)
+		return nil
+	}
+}
+
+func (config *clientConfig) build() (*http.Client, error) {
+	client := &http.Client{}
+	if config.client != nil {
+		copied := *config.client
+		client = &copied
+	}
+	if config.timeout > 0 {
+		client.Timeout = config.timeout
+	}
+
+	transport := config.transport
+	if transport == nil {
+		transport = client.Transport
+	}
+
+	if config.certPath == "" && len(config.certificates) == 0 && config.proxy == nil {
+		client.Transport = transport
+		return client, nil
+	}
+
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	base, ok := transport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("TLS and proxy options require an *http.Transport")
+	}
+	tr := base.Clone()
+
+	if config.proxy != nil {
+		tr.Proxy = config.proxy
+	}
+
+	if config.certPath != "" || len(config.certificates) > 0 {
+		tlsConfig := &tls.Config{}
+		if tr.TLSClientConfig != nil {
+			tlsConfig = tr.TLSClientConfig.Clone()
+		}
+		if config.certPath != "" {
+			certs, err := loadCertPool(config.certPath)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.RootCAs = certs
+		}
+		tlsConfig.Certificates = append(tlsConfig.Certificates, config.certificates...)
+		tr.TLSClientConfig = tlsConfig
+	}
+
+	client.Transport = tr
+	return client, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pemData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	certs := x509.NewCertPool()
+	if !certs.AppendCertsFromPEM(pemData) {
+		return nil, ErrInvalidCertificate
+	}
+	return certs, nil
+}
@@ -0,0 +1,337 @@
+package files
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nextcloud/nextcloudgo"
+)
+
+const (
+	// DefaultChunkSize is used when no chunk size is given
+	DefaultChunkSize = 10 << 20
+	// MinChunkSize is the smallest chunk the server accepts, only the last chunk may be smaller
+	MinChunkSize = 5 << 20
+	// maxChunks is the highest chunk number the server accepts
+	maxChunks = 10000
+)
+
+var (
+	// ErrTooManyChunks when the file needs more than 10000 chunks of the given size
+	ErrTooManyChunks = errors.New("Too many chunks, the chunk size has to be increased")
+	// ErrChunkTooSmall when the chunk size is below MinChunkSize and the file needs more than one chunk
+	ErrChunkTooSmall = errors.New("The chunk size is smaller than the minimum of the server")
+)
+
+// ChunkedUploadOptions configure UploadChunked
+type ChunkedUploadOptions struct {
+	// ChunkSize is the size of every chunk but the last, DefaultChunkSize when 0.
+	// It must not be smaller than MinChunkSize.
+	ChunkSize int64
+	// Parallel is the number of chunks uploaded at the same time, 1 when 0
+	Parallel int
+	// StateFile remembers the upload, so it can be resumed after an interruption
+	// by calling UploadChunked again with the same file, path and chunk size.
+	// It is removed once the upload is complete. No state is kept when empty.
+	// The upload starts over when the server switched between chunking v1 and v2 in between.
+	StateFile string
+	// ModTime is set as modification time of the file when not zero
+	ModTime time.Time
+	// Progress is called after every finished chunk with the uploaded and the total number of bytes
+	Progress func(uploaded, total int64)
+
+	// minChunkSize is MinChunkSize when 0, tests lower it to upload a few bytes
+	minChunkSize int64
+}
+
+// chunks returns the chunk size and the number of chunks for a file of the given size
+func (options ChunkedUploadOptions) chunks(size int64) (int64, int, error) {
+	chunkSize := options.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	minChunkSize := options.minChunkSize
+	if minChunkSize <= 0 {
+		minChunkSize = MinChunkSize
+	}
+
+	chunks := int((size + chunkSize - 1) / chunkSize)
+	if chunks == 0 {
+		chunks = 1
+	}
+	if chunks > maxChunks {
+		return 0, 0, ErrTooManyChunks
+	}
+	if chunks > 1 && chunkSize < minChunkSize {
+		return 0, 0, ErrChunkTooSmall
+	}
+	return chunkSize, chunks, nil
+}
+
+// uploadState is stored in the state file of a resumable upload
+type uploadState struct {
+	ID        string `json:"id"`
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	ChunkSize int64  `json:"chunkSize"`
+	// Destination is set when the upload was started with the target, like servers with chunking v2 expect
+	Destination bool `json:"destination"`
+
+	// user is the id of the user whose uploads folder holds the chunks
+	user string
+}
+
+// UploadChunked uploads size bytes of r in chunks to remote.php/dav/uploads/{user}
+// and assembles them to the file at the given path.
+// Servers older than Nextcloud 26 only learn the target when the chunks are assembled,
+// newer ones get it up front to store the chunks directly, e.g. as S3 multipart upload.
+// Returns ErrParentNotFound when the parent folder does not exist and
+// ErrChunkTooSmall when the chunk size is below MinChunkSize.
+func (f *Files) UploadChunked(name string, r io.ReaderAt, size int64, options ChunkedUploadOptions) error {
+	return f.UploadChunkedContext(context.Background(), name, r, size, options)
+}
+
+// UploadChunkedContext is like UploadChunked but aborts the requests when ctx is done.
+// An upload with a StateFile can be resumed after ctx was cancelled.
+func (f *Files) UploadChunkedContext(ctx context.Context, name string, r io.ReaderAt, size int64, options ChunkedUploadOptions) error {
+	chunkSize, chunks, err := options.chunks(size)
+	if err != nil {
+		return err
+	}
+	user, err := f.userID(ctx)
+	if err != nil {
+		return err
+	}
+
+	// When the version is unknown the target is only sent with the MOVE, which all versions support
+	withDestination, _ := f.nc.SupportsContext(ctx, nextcloudgo.FeatureChunkingV2)
+	header := http.Header{}
+	if withDestination {
+		header.Set("Destination", f.nc.ServerURL+fileURL(user, name))
+		header.Set("OC-Total-Length", strconv.FormatInt(size, 10))
+	}
+
+	state := uploadState{Path: cleanPath(name), Size: size, ChunkSize: chunkSize, Destination: withDestination, user: user}
+	uploaded, err := f.resumeUpload(ctx, &state, options.StateFile, header)
+	if err != nil {
+		return err
+	}
+
+	var (
+		mu       sync.Mutex
+		progress int64
+	)
+	report := func(n int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		progress += n
+		if options.Progress != nil {
+			options.Progress(progress, size)
+		}
+	}
+	for _, n := range uploaded {
+		progress += n
+	}
+	if progress > 0 {
+		report(0)
+	}
+
+	pending := make(chan int, chunks)
+	for i := 0; i < chunks; i++ {
+		if _, ok := uploaded[chunkName(i)]; !ok {
+			pending <- i
+		}
+	}
+	close(pending)
+
+	if err := f.uploadChunks(ctx, state, r, size, header, pending, options.Parallel, report); err != nil {
+		if options.StateFile == "" {
+			f.abortUpload(state)
+		}
+		return err
+	}
+
+	if !options.ModTime.IsZero() {
+		header.Set("X-OC-MTime", strconv.FormatInt(options.ModTime.Unix(), 10))
+	}
+	header.Set("Destination", f.nc.ServerURL+fileURL(user, name))
+	header.Set("OC-Total-Length", strconv.FormatInt(size, 10))
+	header.Set("Overwrite", "T")
+	response, err := f.do(ctx, "assembling the chunks", "MOVE", uploadURL(state)+"/.file", state.Path, nil, header, uploadErrors)
+	if err != nil {
+		if options.StateFile == "" {
+			f.abortUpload(state)
+		}
+		return err
+	}
+	response.Body.Close()
+
+	if options.StateFile != "" {
+		if err := os.Remove(options.StateFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// uploadRoot is the unescaped path of the folder holding the chunks below the server url
+func uploadRoot(state uploadState) string {
+	return davRoot + "/uploads/" + state.user + "/" + state.ID
+}
+
+func uploadURL(state uploadState) string {
+	return escapePath(uploadRoot(state))
+}
+
+// chunkName returns the zero padded, 1-based number of the chunk
+func chunkName(i int) string {
+	return fmt.Sprintf("%05d", i+1)
+}
+
+// resumeUpload continues the upload of the state file, or starts a new one with
+// the Destination of the chunk header.
+// It returns the sizes of the chunks that are already on the server.
+func (f *Files) resumeUpload(ctx context.Context, state *uploadState, stateFile string, header http.Header) (map[string]int64, error) {
+	if stateFile != "" {
+		var saved uploadState
+		if data, err := os.ReadFile(stateFile); err == nil && json.Unmarshal(data, &saved) == nil {
+			if saved.Path == state.Path && saved.Size == state.Size && saved.ChunkSize == state.ChunkSize {
+				saved.user = state.user
+				if saved.Destination != state.Destination {
+					// The server was upgraded or downgraded in between, the chunks can not be assembled with the other protocol
+					f.abortUpload(saved)
+				} else {
+					uploaded, err := f.uploadedChunks(ctx, saved)
+					if err == nil {
+						state.ID = saved.ID
+						return uploaded, nil
+					}
+					if !errors.Is(err, ErrNotFound) {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	state.ID = "nextcloudgo-" + hex.EncodeToString(id)
+
+	mkcolHeader := http.Header{}
+	if destination := header.Get("Destination"); destination != "" {
+		mkcolHeader.Set("Destination", destination)
+	}
+	response, err := f.do(ctx, "starting the upload", "MKCOL", uploadURL(*state), state.Path, nil, mkcolHeader, nil)
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+
+	if stateFile != "" {
+		data, _ := json.Marshal(state)
+		if err := os.WriteFile(stateFile, data, 0600); err != nil {
+			f.abortUpload(*state)
+			return nil, err
+		}
+	}
+	return map[string]int64{}, nil
+}
+
+// uploadedChunks lists the complete chunks of an upload on the server
+func (f *Files) uploadedChunks(ctx context.Context, state uploadState) (map[string]int64, error) {
+	responses, names, err := f.propfind(ctx, "resuming the upload", uploadURL(state), state.Path, uploadRoot(state), propfindBody, DepthOne)
+	if err != nil {
+		return nil, err
+	}
+
+	lastIndex := max(state.Size-1, 0) / state.ChunkSize
+	last, lastSize := chunkName(int(lastIndex)), state.Size-lastIndex*state.ChunkSize
+
+	uploaded := map[string]int64{}
+	for i, r := range responses {
+		info := r.prop().fileInfo(names[i])
+		if info.IsDir {
+			continue
+		}
+		// Incomplete chunks of an interrupted request are uploaded again
+		name := strings.TrimPrefix(names[i], "/")
+		if (name == last && info.Size == lastSize) || (name < last && info.Size == state.ChunkSize) {
+			uploaded[name] = info.Size
+		}
+	}
+	return uploaded, nil
+}
+
+// uploadChunks sends the pending chunks with the given number of parallel requests
+func (f *Files) uploadChunks(ctx context.Context, state uploadState, r io.ReaderAt, size int64, header http.Header, pending <-chan int, parallel int, report func(int64)) error {
+	if parallel <= 0 {
+		parallel = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range pending {
+				if ctx.Err() != nil {
+					return
+				}
+				offset := int64(i) * state.ChunkSize
+				length := min(state.ChunkSize, size-offset)
+
+				chunkHeader := header.Clone()
+				chunkHeader.Set("Content-Length", strconv.FormatInt(length, 10))
+				chunkHeader.Set("Content-Type", "application/octet-stream")
+				body := io.NewSectionReader(r, offset, length)
+
+				response, err := f.do(ctx, "uploading the chunk", http.MethodPut, uploadURL(state)+"/"+chunkName(i), state.Path, body, chunkHeader, uploadErrors)
+				if err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					return
+				}
+				response.Body.Close()
+				report(length)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return firstErr
+}
+
+// abortUpload removes the chunks of a failed upload, errors are ignored
+func (f *Files) abortUpload(state uploadState) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if response, err := f.nc.DAVRequestContext(ctx, http.MethodDelete, uploadURL(state), nil, nil); err == nil {
+		response.Body.Close()
+	}
+}
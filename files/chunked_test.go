@@ -0,0 +1,213 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nextcloud/nextcloudgo"
+	"github.com/nextcloud/nextcloudgo/files/davtest"
+)
+
+// chunkCounter counts the uploaded chunks and the requests with a Destination header
+type chunkCounter struct {
+	mu           sync.Mutex
+	chunks       int
+	destinations int
+}
+
+func (c *chunkCounter) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	if r.Method == http.MethodPut {
+		c.chunks++
+	}
+	if r.Header.Get("Destination") != "" {
+		c.destinations++
+	}
+	c.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestUploadChunked(t *testing.T) {
+	server, api := newTestFiles(t)
+	server.Mkdir("/Backups")
+	content := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	modTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	var mu sync.Mutex
+	var last, total int64
+	err := api.UploadChunked("/Backups/db.dump", bytes.NewReader(content), int64(len(content)), ChunkedUploadOptions{
+		ChunkSize:    5,
+		minChunkSize: 5,
+		Parallel:     3,
+		ModTime:      modTime,
+		Progress: func(uploaded, size int64) {
+			mu.Lock()
+			defer mu.Unlock()
+			if uploaded < last {
+				t.Errorf("Progress went back from %d to %d", last, uploaded)
+			}
+			last, total = uploaded, size
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uploaded, _ := server.ReadFile("/Backups/db.dump"); !bytes.Equal(uploaded, content) {
+		t.Errorf("Chunks were not assembled correctly: %q", uploaded)
+	}
+	if last != int64(len(content)) || total != int64(len(content)) {
+		t.Errorf("Unexpected progress %d of %d", last, total)
+	}
+	if info, _ := api.Stat("/Backups/db.dump"); !info.ModTime.Equal(modTime) {
+		t.Errorf("Unexpected modification time %v", info.ModTime)
+	}
+	if len(server.Uploads()) != 0 {
+		t.Error("The upload folder should be removed")
+	}
+
+	if err := api.UploadChunked("/Missing/db.dump", bytes.NewReader(content), int64(len(content)), ChunkedUploadOptions{ChunkSize: 5, minChunkSize: 5}); !errors.Is(err, ErrParentNotFound) {
+		t.Errorf("Expected ErrParentNotFound, got %v", err)
+	}
+	if len(server.Uploads()) != 0 {
+		t.Error("Failed uploads without state file should be removed")
+	}
+
+	if err := api.UploadChunked("/Backups/db.dump", bytes.NewReader(content), int64(len(content)), ChunkedUploadOptions{ChunkSize: 4, minChunkSize: 5}); !errors.Is(err, ErrChunkTooSmall) {
+		t.Errorf("Expected ErrChunkTooSmall, got %v", err)
+	}
+}
+
+func TestUploadChunkedOldServer(t *testing.T) {
+	server := davtest.NewServer("alice", "secret")
+	defer server.Close()
+	server.Version = "20.0.14"
+
+	counter := &chunkCounter{}
+	nc, err := nextcloudgo.New(server.URL, "alice", "secret", nextcloudgo.WithTransport(counter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := New(nc)
+
+	content := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	if err := api.UploadChunked("/db.dump", bytes.NewReader(content), int64(len(content)), ChunkedUploadOptions{ChunkSize: 5, minChunkSize: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if uploaded, _ := server.ReadFile("/db.dump"); !bytes.Equal(uploaded, content) {
+		t.Errorf("Chunks were not assembled correctly: %q", uploaded)
+	}
+	if counter.destinations != 1 {
+		t.Errorf("Only the MOVE should have a destination on old servers, got %d", counter.destinations)
+	}
+}
+
+func TestUploadChunkedResume(t *testing.T) {
+	server := davtest.NewServer("alice", "secret")
+	defer server.Close()
+
+	counter := &chunkCounter{}
+	nc, err := nextcloudgo.New(server.URL, "alice", "secret", nextcloudgo.WithTransport(counter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := New(nc)
+
+	content := bytes.Repeat([]byte("nextcloud"), 4)
+	stateFile := filepath.Join(t.TempDir(), "upload.json")
+	ctx, cancel := context.WithCancel(context.Background())
+	options := ChunkedUploadOptions{
+		ChunkSize:    8,
+		minChunkSize: 5,
+		StateFile:    stateFile,
+		Progress: func(uploaded, total int64) {
+			if uploaded >= 16 {
+				cancel()
+			}
+		},
+	}
+
+	if err := api.UploadChunkedContext(ctx, "/backup.tar", bytes.NewReader(content), int64(len(content)), options); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the upload to be cancelled, got %v", err)
+	}
+	if _, err := os.Stat(stateFile); err != nil || len(server.Uploads()) != 1 || server.Exists("/backup.tar") {
+		t.Fatal("The interrupted upload should be kept for resuming")
+	}
+
+	var resumed []int64
+	options.Progress = func(uploaded, total int64) {
+		resumed = append(resumed, uploaded)
+	}
+	if err := api.UploadChunked("/backup.tar", bytes.NewReader(content), int64(len(content)), options); err != nil {
+		t.Fatal(err)
+	}
+
+	if uploaded, _ := server.ReadFile("/backup.tar"); !bytes.Equal(uploaded, content) {
+		t.Errorf("Chunks were not assembled correctly: %q", uploaded)
+	}
+	if counter.chunks != 5 {
+		t.Errorf("Only the missing chunks should be uploaded again, got %d chunks in total", counter.chunks)
+	}
+	if len(resumed) == 0 || resumed[0] != 16 {
+		t.Errorf("Progress should start with the resumed bytes, got %v", resumed)
+	}
+	if _, err := os.Stat(stateFile); !errors.Is(err, os.ErrNotExist) {
+		t.Error("The state file should be removed after the upload")
+	}
+}
+
+func TestUploadChunkedResumeAfterUpgrade(t *testing.T) {
+	server := davtest.NewServer("alice", "secret")
+	defer server.Close()
+	server.Version = "25.0.13"
+
+	counter := &chunkCounter{}
+	nc, err := nextcloudgo.New(server.URL, "alice", "secret", nextcloudgo.WithTransport(counter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := New(nc)
+
+	content := bytes.Repeat([]byte("nextcloud"), 4)
+	stateFile := filepath.Join(t.TempDir(), "upload.json")
+	ctx, cancel := context.WithCancel(context.Background())
+	options := ChunkedUploadOptions{
+		ChunkSize:    8,
+		minChunkSize: 5,
+		StateFile:    stateFile,
+		Progress: func(uploaded, total int64) {
+			if uploaded >= 16 {
+				cancel()
+			}
+		},
+	}
+	if err := api.UploadChunkedContext(ctx, "/backup.tar", bytes.NewReader(content), int64(len(content)), options); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the upload to be cancelled, got %v", err)
+	}
+	interrupted := counter.chunks
+
+	// The upgraded server expects the target up front, so the v1 upload is not continued
+	server.Version = "28.0.4"
+	nc, _ = nextcloudgo.New(server.URL, "alice", "secret", nextcloudgo.WithTransport(counter))
+	api = New(nc)
+	options.Progress = nil
+	if err := api.UploadChunked("/backup.tar", bytes.NewReader(content), int64(len(content)), options); err != nil {
+		t.Fatal(err)
+	}
+	if uploaded, _ := server.ReadFile("/backup.tar"); !bytes.Equal(uploaded, content) {
+		t.Errorf("Chunks were not assembled correctly: %q", uploaded)
+	}
+	if counter.chunks-interrupted != 5 {
+		t.Errorf("All chunks should be uploaded again, got %d", counter.chunks-interrupted)
+	}
+	if len(server.Uploads()) != 0 {
+		t.Error("The v1 upload should be removed")
+	}
+}
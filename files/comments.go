@@ -0,0 +1,227 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const commentsPropfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:oc="http://owncloud.org/ns">
+	<d:prop>
+		<oc:id/>
+		<oc:parentId/>
+		<oc:message/>
+		<oc:verb/>
+		<oc:actorType/>
+		<oc:actorId/>
+		<oc:actorDisplayName/>
+		<oc:creationDateTime/>
+		<oc:objectId/>
+		<oc:isUnread/>
+		<oc:mentions/>
+	</d:prop>
+</d:propfind>`
+
+// Comment is a comment on a file or folder
+type Comment struct {
+	ID     int64
+	FileID int64
+	// ParentID is the comment this one replies to, 0 for top level comments
+	ParentID int64
+	Message  string
+	// Verb is "comment" for comments written by users, other verbs are used by apps
+	Verb string
+	// Actor is who wrote the comment
+	Actor        CommentActor
+	CreationTime time.Time
+	// Unread is true when the comment was posted after the logged in user last read the comments
+	Unread bool
+	// Mentions are the users and groups mentioned in the message, e.g. with @alice
+	Mentions []Mention
+}
+
+// CommentActor is the author of a comment
+type CommentActor struct {
+	// Type is "users" for comments written by users
+	Type        string
+	ID          string
+	DisplayName string
+}
+
+// Mention is a user or group mentioned in a comment
+type Mention struct {
+	// Type is "user", "group", "guest" or "federated_user"
+	Type        string
+	ID          string
+	DisplayName string
+}
+
+func (p davProp) comment() Comment {
+	comment := Comment{
+		Message:  p.Message,
+		Verb:     p.Verb,
+		Actor:    CommentActor{Type: p.ActorType, ID: p.ActorID, DisplayName: p.ActorDisplayName},
+		Unread:   p.IsUnread == "true",
+		Mentions: []Mention{},
+	}
+	comment.ID, _ = strconv.ParseInt(p.ID, 10, 64)
+	comment.FileID, _ = strconv.ParseInt(p.ObjectID, 10, 64)
+	comment.ParentID, _ = strconv.ParseInt(p.ParentID, 10, 64)
+	// The server formats dates according to RFC 2822
+	if t, err := time.Parse(time.RFC1123Z, p.CreationDateTime); err == nil {
+		comment.CreationTime = t
+	} else {
+		comment.CreationTime, _ = http.ParseTime(p.CreationDateTime)
+	}
+	for _, m := range p.Mentions {
+		comment.Mentions = append(comment.Mentions, Mention{Type: m.Type, ID: m.ID, DisplayName: m.DisplayName})
+	}
+	return comment
+}
+
+func commentsURL(fileID int64) string {
+	return davRoot + "/comments/files/" + strconv.FormatInt(fileID, 10)
+}
+
+func commentPath(fileID, commentID int64) string {
+	return "/" + strconv.FormatInt(fileID, 10) + "/" + strconv.FormatInt(commentID, 10)
+}
+
+// ListComments returns the comments of the file with the given id, newest first.
+// It skips offset comments and returns at most limit comments, the server caps limit at 100.
+// Returns ErrNotFound when the file does not exist
+func (f *Files) ListComments(fileID int64, limit, offset int) ([]Comment, error) {
+	return f.ListCommentsContext(context.Background(), fileID, limit, offset)
+}
+
+// ListCommentsContext is like ListComments but aborts the request when ctx is done
+func (f *Files) ListCommentsContext(ctx context.Context, fileID int64, limit, offset int) ([]Comment, error) {
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<oc:filter-comments xmlns:d="DAV:" xmlns:oc="http://owncloud.org/ns">
+	<oc:limit>` + strconv.Itoa(limit) + `</oc:limit>
+	<oc:offset>` + strconv.Itoa(offset) + `</oc:offset>
+</oc:filter-comments>`
+	header := http.Header{"Content-Type": {"application/xml; charset=utf-8"}}
+	response, err := f.do(ctx, "listing the comments", "REPORT", commentsURL(fileID), idPath(fileID), strings.NewReader(body), header, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	responses, _, err := parseMultistatus(response.Body, "")
+	if err != nil {
+		return nil, err
+	}
+
+	comments := make([]Comment, 0, len(responses))
+	for _, r := range responses {
+		if comment := r.prop().comment(); comment.ID != 0 {
+			comments = append(comments, comment)
+		}
+	}
+	return comments, nil
+}
+
+// GetComment returns a single comment of the file with the given id
+// Returns ErrNotFound when the file or the comment does not exist
+func (f *Files) GetComment(fileID, commentID int64) (Comment, error) {
+	return f.GetCommentContext(context.Background(), fileID, commentID)
+}
+
+// GetCommentContext is like GetComment but aborts the request when ctx is done
+func (f *Files) GetCommentContext(ctx context.Context, fileID, commentID int64) (Comment, error) {
+	u := commentsURL(fileID) + "/" + strconv.FormatInt(commentID, 10)
+	responses, _, err := f.propfind(ctx, "getting the comment", u, commentPath(fileID, commentID), "", commentsPropfindBody, DepthZero)
+	if err != nil {
+		return Comment{}, err
+	}
+	if len(responses) == 0 {
+		return Comment{}, errors.New("Comment not found in response")
+	}
+	return responses[0].prop().comment(), nil
+}
+
+// PostComment adds a comment by the logged in user to the file with the given id.
+// Users can be mentioned with @ followed by their id.
+// Returns ErrNotFound when the file does not exist
+func (f *Files) PostComment(fileID int64, message string) (Comment, error) {
+	return f.PostCommentContext(context.Background(), fileID, message)
+}
+
+// PostCommentContext is like PostComment but aborts the requests when ctx is done
+func (f *Files) PostCommentContext(ctx context.Context, fileID int64, message string) (Comment, error) {
+	body, err := json.Marshal(map[string]string{"actorType": "users", "verb": "comment", "message": message})
+	if err != nil {
+		return Comment{}, err
+	}
+
+	header := http.Header{"Content-Type": {"application/json"}}
+	response, err := f.do(ctx, "posting the comment", http.MethodPost, commentsURL(fileID), idPath(fileID), bytes.NewReader(body), header, nil)
+	if err != nil {
+		return Comment{}, err
+	}
+	response.Body.Close()
+
+	// The server returns the url of the new comment, e.g. /remote.php/dav/comments/files/12/34
+	id, err := strconv.ParseInt(path.Base(response.Header.Get("Content-Location")), 10, 64)
+	if err != nil {
+		return Comment{}, errors.New("Comment id not found in response")
+	}
+	return f.GetCommentContext(ctx, fileID, id)
+}
+
+// EditComment replaces the message of a comment, only the author may edit it
+// Returns ErrNotFound when the file or the comment does not exist and
+// ErrInsufficientPermissions when the comment was written by someone else
+func (f *Files) EditComment(fileID, commentID int64, message string) error {
+	return f.EditCommentContext(context.Background(), fileID, commentID, message)
+}
+
+// EditCommentContext is like EditComment but aborts the request when ctx is done
+func (f *Files) EditCommentContext(ctx context.Context, fileID, commentID int64, message string) error {
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<d:propertyupdate xmlns:d="DAV:" xmlns:oc="http://owncloud.org/ns">
+	<d:set><d:prop><oc:message>` + escapeXML(message) + `</oc:message></d:prop></d:set>
+</d:propertyupdate>`
+	u := commentsURL(fileID) + "/" + strconv.FormatInt(commentID, 10)
+	return f.proppatch(ctx, "editing the comment", u, commentPath(fileID, commentID), body)
+}
+
+// DeleteComment removes a comment, only the author may delete it
+// Returns ErrNotFound when the file or the comment does not exist and
+// ErrInsufficientPermissions when the comment was written by someone else
+func (f *Files) DeleteComment(fileID, commentID int64) error {
+	return f.DeleteCommentContext(context.Background(), fileID, commentID)
+}
+
+// DeleteCommentContext is like DeleteComment but aborts the request when ctx is done
+func (f *Files) DeleteCommentContext(ctx context.Context, fileID, commentID int64) error {
+	u := commentsURL(fileID) + "/" + strconv.FormatInt(commentID, 10)
+	response, err := f.do(ctx, "deleting the comment", http.MethodDelete, u, commentPath(fileID, commentID), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// MarkCommentsRead marks all comments of the file with the given id as read by the logged in user
+// Returns ErrNotFound when the file does not exist
+func (f *Files) MarkCommentsRead(fileID int64) error {
+	return f.MarkCommentsReadContext(context.Background(), fileID)
+}
+
+// MarkCommentsReadContext is like MarkCommentsRead but aborts the request when ctx is done
+func (f *Files) MarkCommentsReadContext(ctx context.Context, fileID int64) error {
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<d:propertyupdate xmlns:d="DAV:" xmlns:oc="http://owncloud.org/ns">
+	<d:set><d:prop><oc:readMarker>` + time.Now().UTC().Format(http.TimeFormat) + `</oc:readMarker></d:prop></d:set>
+</d:propertyupdate>`
+	return f.proppatch(ctx, "marking the comments as read", commentsURL(fileID), idPath(fileID), body)
+}
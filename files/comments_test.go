@@ -0,0 +1,77 @@
+package files
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestComments(t *testing.T) {
+	server, api := newTestFiles(t)
+	server.WriteFile("/Uploads/thesis.pdf", []byte("draft"))
+	fileID := server.FileID("/Uploads/thesis.pdf")
+	bobs := server.AddComment("/Uploads/thesis.pdf", "bob", "Please check chapter 2")
+
+	comment, err := api.PostComment(fileID, `Looks good @bob and @"carol smith"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment.ID == 0 || comment.FileID != fileID || comment.Verb != "comment" || time.Since(comment.CreationTime) > time.Minute {
+		t.Errorf("Unexpected comment %+v", comment)
+	}
+	if comment.Actor != (CommentActor{Type: "users", ID: "alice", DisplayName: "alice"}) {
+		t.Errorf("Unexpected actor %+v", comment.Actor)
+	}
+	expected := []Mention{{Type: "user", ID: "bob", DisplayName: "bob"}, {Type: "user", ID: "carol smith", DisplayName: "carol smith"}}
+	if !reflect.DeepEqual(comment.Mentions, expected) {
+		t.Errorf("Unexpected mentions %+v", comment.Mentions)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := api.PostComment(fileID, "Ping"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, err := api.ListComments(fileID, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Newest first, so the page holds the first ping, the comment of alice and the one of bob
+	if len(page) != 3 || page[0].Message != "Ping" || page[1].ID != comment.ID || page[2].ID != bobs {
+		t.Fatalf("Unexpected page %+v", page)
+	}
+	if page[2].Actor.ID != "bob" || !page[2].Unread {
+		t.Errorf("Unexpected comment %+v", page[2])
+	}
+
+	if err := api.MarkCommentsRead(fileID); err != nil {
+		t.Fatal(err)
+	}
+	if read, _ := api.GetComment(fileID, bobs); read.Unread {
+		t.Error("The comment should be read")
+	}
+
+	if err := api.EditComment(fileID, comment.ID, "Approved"); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.EditComment(fileID, bobs, "Hijacked"); !errors.Is(err, ErrInsufficientPermissions) {
+		t.Errorf("Expected ErrInsufficientPermissions, got %v", err)
+	}
+	if err := api.DeleteComment(fileID, bobs); !errors.Is(err, ErrInsufficientPermissions) {
+		t.Errorf("Expected ErrInsufficientPermissions, got %v", err)
+	}
+	if err := api.DeleteComment(fileID, comment.ID+1); err != nil {
+		t.Fatal(err)
+	}
+	if messages := server.Comments("/Uploads/thesis.pdf"); !reflect.DeepEqual(messages, []string{"Please check chapter 2", "Approved", "Ping", "Ping"}) {
+		t.Errorf("Unexpected comments %v", messages)
+	}
+
+	if _, err := api.ListComments(999, 10, 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if _, err := api.GetComment(fileID, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package davtest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// comment is a comment on a file by a user
+type comment struct {
+	id      int64
+	actor   string
+	message string
+	created time.Time
+}
+
+// mentionPattern finds mentions like @alice or @"Alice Smith" in messages
+var mentionPattern = regexp.MustCompile(`(?:^|\s)@("[^"]+"|[a-zA-Z0-9_.@'-]+)`)
+
+// AddComment adds a comment by the given user to a file or folder and returns its id, 0 when it does not exist
+func (s *Server) AddComment(name, actor, message string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.nodes[clean(name)]
+	if !ok {
+		return 0
+	}
+	return s.addComment(n, actor, message).id
+}
+
+func (s *Server) addComment(n *node, actor, message string) *comment {
+	s.lastCommentID++
+	c := &comment{id: s.lastCommentID, actor: actor, message: message, created: time.Now().UTC().Truncate(time.Second)}
+	n.comments = append(n.comments, c)
+	return c
+}
+
+// Comments returns the messages of the comments of a file or folder, oldest first
+func (s *Server) Comments(name string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := []string{}
+	if n, ok := s.nodes[clean(name)]; ok {
+		for _, c := range n.comments {
+			messages = append(messages, c.message)
+		}
+	}
+	return messages
+}
+
+func (s *Server) commentProps(n *node, c *comment) string {
+	var mentions strings.Builder
+	for _, match := range mentionPattern.FindAllStringSubmatch(c.message, -1) {
+		id := strings.Trim(match[1], `"`)
+		fmt.Fprintf(&mentions, "<oc:mention><oc:mentionType>user</oc:mentionType><oc:mentionId>%s</oc:mentionId><oc:mentionDisplayName>%s</oc:mentionDisplayName></oc:mention>",
+			escape(id), escape(id))
+	}
+	unread := n.readMarker.IsZero() || c.created.After(n.readMarker)
+	return fmt.Sprintf("<oc:id>%d</oc:id><oc:parentId>0</oc:parentId><oc:topmostParentId>0</oc:topmostParentId><oc:childrenCount>0</oc:childrenCount>"+
+		"<oc:message>%s</oc:message><oc:verb>comment</oc:verb>"+
+		"<oc:actorType>users</oc:actorType><oc:actorId>%s</oc:actorId><oc:actorDisplayName>%s</oc:actorDisplayName>"+
+		"<oc:creationDateTime>%s</oc:creationDateTime><oc:objectType>files</oc:objectType><oc:objectId>%d</oc:objectId>"+
+		"<oc:isUnread>%t</oc:isUnread><oc:mentions>%s</oc:mentions>",
+		c.id, escape(c.message), escape(c.actor), escape(c.actor), c.created.Format(time.RFC1123Z), n.id, unread, mentions.String())
+}
+
+func (s *Server) serveComments(w http.ResponseWriter, r *http.Request, name string) {
+	const root = "/remote.php/dav/comments/files/"
+	parts := strings.Split(strings.TrimPrefix(name, "/"), "/")
+	if parts[0] != "files" || len(parts) < 2 || len(parts) > 3 {
+		writeError(w, http.StatusNotFound, "NotFound", "Node with name "+name+" could not be located")
+		return
+	}
+	_, n := s.nodeByID(parts[1])
+	if n == nil {
+		writeError(w, http.StatusNotFound, "NotFound", "File with id "+parts[1]+" not found")
+		return
+	}
+
+	if len(parts) == 2 {
+		switch r.Method {
+		case "REPORT":
+			s.reportComments(w, r, n, root+parts[1])
+		case http.MethodPost:
+			var body struct {
+				ActorType string `json:"actorType"`
+				Verb      string `json:"verb"`
+				Message   string `json:"message"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.ActorType != "users" || body.Verb != "comment" {
+				writeError(w, http.StatusBadRequest, "BadRequest", "Invalid comment")
+				return
+			}
+			c := s.addComment(n, s.id(), body.Message)
+			w.Header().Set("Content-Location", root+parts[1]+"/"+strconv.FormatInt(c.id, 10))
+			w.WriteHeader(http.StatusCreated)
+		case "PROPPATCH":
+			patchProps(w, r, root+parts[1], func(prop xmlNode, set bool) bool {
+				t, err := http.ParseTime(strings.TrimSpace(prop.Text))
+				if prop.XMLName.Local != "readMarker" || !set || err != nil {
+					return false
+				}
+				n.readMarker = t
+				return true
+			})
+		default:
+			writeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" is not supported by davtest")
+		}
+		return
+	}
+
+	index := -1
+	for i, c := range n.comments {
+		if strconv.FormatInt(c.id, 10) == parts[2] {
+			index = i
+		}
+	}
+	if index < 0 {
+		writeError(w, http.StatusNotFound, "NotFound", "Comment "+parts[2]+" not found")
+		return
+	}
+	c := n.comments[index]
+	if r.Method != "PROPFIND" && c.actor != s.id() {
+		writeError(w, http.StatusForbidden, "Forbidden", "Only authors are allowed to edit their comment.")
+		return
+	}
+
+	switch r.Method {
+	case "PROPFIND":
+		writeMultistatus(w, []string{response(root+parts[1]+"/"+parts[2], false, s.commentProps(n, c))})
+	case "PROPPATCH":
+		patchProps(w, r, root+parts[1]+"/"+parts[2], func(prop xmlNode, set bool) bool {
+			if prop.XMLName.Local != "message" || !set {
+				return false
+			}
+			c.message = prop.Text
+			return true
+		})
+	case http.MethodDelete:
+		n.comments = append(n.comments[:index], n.comments[index+1:]...)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" is not supported by davtest")
+	}
+}
+
+// reportComments answers oc:filter-comments with the comments of the file, newest first
+func (s *Server) reportComments(w http.ResponseWriter, r *http.Request, n *node, href string) {
+	var filter xmlNode
+	if err := xml.NewDecoder(r.Body).Decode(&filter); err != nil || filter.XMLName.Local != "filter-comments" {
+		writeError(w, http.StatusBadRequest, "BadRequest", "Only oc:filter-comments reports are supported")
+		return
+	}
+	limit, offset := 20, 0
+	if l, ok := filter.child("limit"); ok {
+		limit, _ = strconv.Atoi(strings.TrimSpace(l.Text))
+	}
+	if o, ok := filter.child("offset"); ok {
+		offset, _ = strconv.Atoi(strings.TrimSpace(o.Text))
+	}
+
+	comments := append([]*comment(nil), n.comments...)
+	sort.SliceStable(comments, func(i, j int) bool { return comments[i].id > comments[j].id })
+	comments = comments[min(offset, len(comments)):]
+	comments = comments[:min(limit, len(comments))]
+
+	responses := make([]string, len(comments))
+	for i, c := range comments {
+		responses[i] = response(href+"/"+strconv.FormatInt(c.id, 10), false, s.commentProps(n, c))
+	}
+	writeMultistatus(w, responses)
+}
@@ -0,0 +1,549 @@
+// Package davtest provides an in-memory Nextcloud WebDAV server for tests,
+// in the spirit of net/http/httptest.
+package davtest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is a fake Nextcloud serving the files of a single user below
+// remote.php/dav/files/{user}, their chunked uploads, versions and the trash bin.
+// It also answers DAV searches and manages favorites, system tags and comments.
+// Its zero value is not usable, use NewServer.
+type Server struct {
+	*httptest.Server
+
+	User     string
+	Password string
+	// ID is the user id in the DAV urls, which differs from the login User e.g. for LDAP logins.
+	// It is User when empty.
+	ID string
+	// Version is reported by status.php, change it before the first request
+	Version string
+
+	mu       sync.Mutex
+	nodes    map[string]*node
+	lastID   int64
+	revision int64
+	uploads  map[string]*upload
+	trash    map[string]*trashItem
+
+	tags          map[int64]*tag
+	lastTagID     int64
+	lastCommentID int64
+}
+
+type node struct {
+	id       int64
+	dir      bool
+	content  []byte
+	modTime  time.Time
+	etag     string
+	favorite bool
+	versions []*version
+	tags     map[int64]bool
+	comments []*comment
+	// readMarker is when the user last read the comments
+	readMarker time.Time
+}
+
+// NewServer starts a server with an empty home folder for the given user.
+// The caller should call Close when finished, to shut it down.
+func NewServer(user, password string) *Server {
+	s := &Server{User: user, Password: password, Version: "28.0.4.1", nodes: map[string]*node{}}
+	s.nodes["/"] = s.newNode(true, nil, time.Now())
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+func (s *Server) newNode(dir bool, content []byte, modTime time.Time) *node {
+	s.lastID++
+	s.revision++
+	return &node{
+		id:      s.lastID,
+		dir:     dir,
+		content: content,
+		modTime: modTime.UTC().Truncate(time.Second),
+		etag:    strconv.FormatInt(s.revision, 16),
+	}
+}
+
+// touch gives the node and all its parents a new etag, like the server does on changes
+func (s *Server) touch(p string, modTime time.Time) {
+	for {
+		if n, ok := s.nodes[p]; ok {
+			s.revision++
+			n.etag = strconv.FormatInt(s.revision, 16)
+			if n.dir {
+				n.modTime = modTime.UTC().Truncate(time.Second)
+			}
+		}
+		if p == "/" {
+			return
+		}
+		p = path.Dir(p)
+	}
+}
+
+// WriteFile creates or replaces a file, creating missing parent folders
+func (s *Server) WriteFile(name string, content []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name = clean(name)
+	s.mkdirAll(path.Dir(name))
+	s.writeFile(name, content, time.Now())
+}
+
+func (s *Server) writeFile(name string, content []byte, modTime time.Time) *node {
+	if n, ok := s.nodes[name]; ok {
+		n.addVersion()
+		n.content = content
+		n.modTime = modTime.UTC().Truncate(time.Second)
+		s.touch(name, time.Now())
+		return n
+	}
+	n := s.newNode(false, content, modTime)
+	s.nodes[name] = n
+	s.touch(path.Dir(name), time.Now())
+	return n
+}
+
+// Mkdir creates a folder and all missing parents
+func (s *Server) Mkdir(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mkdirAll(clean(name))
+}
+
+func (s *Server) mkdirAll(name string) {
+	if _, ok := s.nodes[name]; ok {
+		return
+	}
+	s.mkdirAll(path.Dir(name))
+	s.nodes[name] = s.newNode(true, nil, time.Now())
+	s.touch(path.Dir(name), time.Now())
+}
+
+// ReadFile returns the contents of a file, false when it does not exist or is a folder
+func (s *Server) ReadFile(name string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.nodes[clean(name)]
+	if !ok || n.dir {
+		return nil, false
+	}
+	return n.content, true
+}
+
+// Exists returns true when a file or folder exists
+func (s *Server) Exists(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.nodes[clean(name)]
+	return ok
+}
+
+// SetFavorite marks a file or folder as favorite
+func (s *Server) SetFavorite(name string, favorite bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n, ok := s.nodes[clean(name)]; ok {
+		n.favorite = favorite
+	}
+}
+
+// FileID returns the id of a file or folder, 0 when it does not exist
+func (s *Server) FileID(name string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n, ok := s.nodes[clean(name)]; ok {
+		return n.id
+	}
+	return 0
+}
+
+func clean(p string) string {
+	return path.Clean("/" + p)
+}
+
+// ServeHTTP answers status.php and the WebDAV requests of the user
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path == "/status.php" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"installed": true, "maintenance": false, "version": s.Version})
+		return
+	}
+
+	user, password, ok := r.BasicAuth()
+	if !ok || user != s.User || password != s.Password {
+		w.Header().Set("WWW-Authenticate", `Basic realm="Nextcloud"`)
+		writeError(w, http.StatusUnauthorized, "NotAuthenticated", "No public access to this resource.")
+		return
+	}
+
+	root := s.filesRoot()
+	switch {
+	case r.URL.Path == root || strings.HasPrefix(r.URL.Path, root+"/"):
+		s.serveFiles(w, r, clean(strings.TrimPrefix(r.URL.Path, root)))
+	case r.Method == "SEARCH" && strings.TrimSuffix(r.URL.Path, "/") == "/remote.php/dav":
+		s.serveSearch(w, r)
+	case r.Method == "PROPFIND" && strings.TrimSuffix(r.URL.Path, "/") == "/remote.php/dav":
+		principal := "<d:current-user-principal><d:href>" + escape(escapePath("/remote.php/dav/principals/users/"+s.id())) + "/</d:href></d:current-user-principal>"
+		writeMultistatus(w, []string{response("/remote.php/dav/", true, principal)})
+	case r.URL.Path == "/remote.php/dav/systemtags" || strings.HasPrefix(r.URL.Path, "/remote.php/dav/systemtags/"):
+		s.serveSystemTags(w, r, clean(strings.TrimPrefix(r.URL.Path, "/remote.php/dav/systemtags")))
+	case strings.HasPrefix(r.URL.Path, "/remote.php/dav/systemtags-relations/"):
+		s.serveTagRelations(w, r, clean(strings.TrimPrefix(r.URL.Path, "/remote.php/dav/systemtags-relations")))
+	case strings.HasPrefix(r.URL.Path, "/remote.php/dav/comments/"):
+		s.serveComments(w, r, clean(strings.TrimPrefix(r.URL.Path, "/remote.php/dav/comments")))
+	case strings.HasPrefix(r.URL.Path, s.versionsRoot()+"/"):
+		s.serveVersions(w, r, clean(strings.TrimPrefix(r.URL.Path, s.versionsRoot())))
+	case strings.HasPrefix(r.URL.Path, s.trashbinRoot()+"/"):
+		s.serveTrashbin(w, r, clean(strings.TrimPrefix(r.URL.Path, s.trashbinRoot())))
+	case strings.HasPrefix(r.URL.Path, s.uploadsRoot()+"/"):
+		s.serveUploads(w, r, clean(strings.TrimPrefix(r.URL.Path, s.uploadsRoot())))
+	default:
+		writeError(w, http.StatusNotFound, "NotFound", "Principal with name "+r.URL.Path+" not found")
+	}
+}
+
+func (s *Server) id() string {
+	if s.ID == "" {
+		return s.User
+	}
+	return s.ID
+}
+
+func (s *Server) filesRoot() string {
+	return "/remote.php/dav/files/" + s.id()
+}
+
+func (s *Server) serveFiles(w http.ResponseWriter, r *http.Request, name string) {
+	n, exists := s.nodes[name]
+	parent, hasParent := s.nodes[path.Dir(name)]
+	hasParent = hasParent && parent.dir
+
+	switch r.Method {
+	case "PROPFIND":
+		if !exists {
+			writeError(w, http.StatusNotFound, "NotFound", "File with name "+name+" could not be located")
+			return
+		}
+		s.propfind(w, name, r.Header.Get("Depth"))
+	case "PROPPATCH", "REPORT":
+		if !exists {
+			writeError(w, http.StatusNotFound, "NotFound", "File with name "+name+" could not be located")
+			return
+		}
+		if r.Method == "PROPPATCH" {
+			s.proppatch(w, r, name)
+		} else {
+			s.report(w, r, name)
+		}
+	case http.MethodGet, http.MethodHead:
+		if !exists {
+			writeError(w, http.StatusNotFound, "NotFound", "File with name "+name+" could not be located")
+			return
+		}
+		if n.dir {
+			writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "Downloading folders is not supported")
+			return
+		}
+		w.Header().Set("Content-Type", contentType(name))
+		w.Header().Set("Content-Length", strconv.Itoa(len(n.content)))
+		w.Header().Set("ETag", `"`+n.etag+`"`)
+		w.Header().Set("Last-Modified", n.modTime.Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			w.Write(n.content)
+		}
+	case http.MethodPut:
+		if !hasParent {
+			writeError(w, http.StatusConflict, "Conflict", "Files can only be created as children of collections")
+			return
+		}
+		if exists && n.dir {
+			writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "Cannot overwrite a folder with a file")
+			return
+		}
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+			return
+		}
+		modTime := time.Now()
+		if mtime, err := strconv.ParseInt(r.Header.Get("X-OC-MTime"), 10, 64); err == nil {
+			modTime = time.Unix(mtime, 0)
+			w.Header().Set("X-OC-MTime", "accepted")
+		}
+		n = s.writeFile(name, content, modTime)
+		w.Header().Set("ETag", `"`+n.etag+`"`)
+		w.Header().Set("OC-ETag", `"`+n.etag+`"`)
+		w.Header().Set("OC-FileId", fileID(n.id))
+		if exists {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.WriteHeader(http.StatusCreated)
+		}
+	case "MKCOL":
+		if exists {
+			writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "The resource you tried to create already exists")
+			return
+		}
+		if !hasParent {
+			writeError(w, http.StatusConflict, "Conflict", "Parent node does not exist")
+			return
+		}
+		s.nodes[name] = s.newNode(true, nil, time.Now())
+		s.touch(path.Dir(name), time.Now())
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		if !exists {
+			writeError(w, http.StatusNotFound, "NotFound", "File with name "+name+" could not be located")
+			return
+		}
+		if name == "/" {
+			writeError(w, http.StatusForbidden, "Forbidden", "The home folder can not be deleted")
+			return
+		}
+		s.moveToTrash(name)
+		w.WriteHeader(http.StatusNoContent)
+	case "MOVE", "COPY":
+		s.transfer(w, r, name)
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" is not supported by davtest")
+	}
+}
+
+// remove deletes the node with all its children and returns them keyed by their path below name
+func (s *Server) remove(name string) map[string]*node {
+	removed := map[string]*node{}
+	for p, n := range s.nodes {
+		if p == name || strings.HasPrefix(p, name+"/") {
+			removed[strings.TrimPrefix(p, name)] = n
+			delete(s.nodes, p)
+		}
+	}
+	s.touch(path.Dir(name), time.Now())
+	return removed
+}
+
+// destination returns the path of the Destination header below the files root
+func (s *Server) destination(r *http.Request) (string, bool) {
+	u, err := url.Parse(r.Header.Get("Destination"))
+	if err != nil {
+		return "", false
+	}
+	root := s.filesRoot()
+	if u.Path != root && !strings.HasPrefix(u.Path, root+"/") {
+		return "", false
+	}
+	return clean(strings.TrimPrefix(u.Path, root)), true
+}
+
+func (s *Server) transfer(w http.ResponseWriter, r *http.Request, name string) {
+	if _, ok := s.nodes[name]; !ok {
+		writeError(w, http.StatusNotFound, "NotFound", "File with name "+name+" could not be located")
+		return
+	}
+	target, ok := s.destination(r)
+	if !ok || name == "/" || target == name || strings.HasPrefix(target, name+"/") {
+		writeError(w, http.StatusBadRequest, "BadRequest", "Invalid destination")
+		return
+	}
+	if parent, ok := s.nodes[path.Dir(target)]; !ok || !parent.dir {
+		writeError(w, http.StatusConflict, "Conflict", "The destination node is not found")
+		return
+	}
+	_, overwritten := s.nodes[target]
+	if overwritten {
+		if r.Header.Get("Overwrite") == "F" {
+			writeError(w, http.StatusPreconditionFailed, "PreconditionFailed", "The destination node already exists, and the overwrite header is set to false")
+			return
+		}
+		s.remove(target)
+	}
+
+	moved := map[string]*node{}
+	for p, n := range s.nodes {
+		if p != name && !strings.HasPrefix(p, name+"/") {
+			continue
+		}
+		if r.Method == "COPY" {
+			copied := s.newNode(n.dir, append([]byte(nil), n.content...), n.modTime)
+			copied.favorite = n.favorite
+			n = copied
+		} else {
+			delete(s.nodes, p)
+		}
+		moved[target+strings.TrimPrefix(p, name)] = n
+	}
+	for p, n := range moved {
+		s.nodes[p] = n
+	}
+	if r.Method == "MOVE" {
+		s.touch(path.Dir(name), time.Now())
+	}
+	s.touch(target, time.Now())
+
+	if overwritten {
+		w.WriteHeader(http.StatusNoContent)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+func (s *Server) propfind(w http.ResponseWriter, name, depth string) {
+	names := []string{name}
+	if s.nodes[name].dir && depth != "0" {
+		for p := range s.nodes {
+			if p == name || !strings.HasPrefix(p, strings.TrimSuffix(name, "/")+"/") {
+				continue
+			}
+			if depth == "1" && path.Dir(p) != name {
+				continue
+			}
+			names = append(names, p)
+		}
+	}
+	sort.Strings(names)
+
+	responses := make([]string, len(names))
+	for i, p := range names {
+		responses[i] = response(s.filesRoot()+escapePath(p), s.nodes[p].dir, s.fileProps(p))
+	}
+	writeMultistatus(w, responses)
+}
+
+func (s *Server) fileProps(name string) string {
+	n := s.nodes[name]
+	favorite := 0
+	if n.favorite {
+		favorite = 1
+	}
+
+	props := fmt.Sprintf("<d:getetag>&quot;%s&quot;</d:getetag>"+
+		"<d:getlastmodified>%s</d:getlastmodified>"+
+		"<oc:fileid>%d</oc:fileid>"+
+		"<oc:id>%s</oc:id>"+
+		"<oc:size>%d</oc:size>"+
+		"<oc:favorite>%d</oc:favorite>"+
+		"<oc:owner-id>%s</oc:owner-id>",
+		n.etag, n.modTime.Format(http.TimeFormat), n.id, fileID(n.id), s.size(name), favorite, escape(s.id()))
+	if n.dir {
+		return props + "<d:resourcetype><d:collection/></d:resourcetype><oc:permissions>RGDNVCK</oc:permissions>"
+	}
+	return props + fmt.Sprintf("<d:resourcetype/><oc:permissions>RGDNVW</oc:permissions>"+
+		"<d:getcontentlength>%d</d:getcontentlength><d:getcontenttype>%s</d:getcontenttype>",
+		len(n.content), escape(contentType(name)))
+}
+
+// size returns the size of a file or the total size of all files in a folder
+func (s *Server) size(name string) int {
+	size := 0
+	for p, n := range s.nodes {
+		if p == name || strings.HasPrefix(p, strings.TrimSuffix(name, "/")+"/") {
+			size += len(n.content)
+		}
+	}
+	return size
+}
+
+func fileID(id int64) string {
+	return fmt.Sprintf("%08docdavtest", id)
+}
+
+func contentType(name string) string {
+	if t := mime.TypeByExtension(path.Ext(name)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func response(href string, dir bool, props string) string {
+	if dir && !strings.HasSuffix(href, "/") {
+		href += "/"
+	}
+	return "<d:response><d:href>" + escape(href) + "</d:href>" +
+		"<d:propstat><d:prop>" + props + "</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>" +
+		"</d:response>"
+}
+
+func writeMultistatus(w http.ResponseWriter, responses []string) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, `<?xml version="1.0"?>`+"\n"+
+		`<d:multistatus xmlns:d="DAV:" xmlns:s="http://sabredav.org/ns" xmlns:oc="http://owncloud.org/ns" xmlns:nc="http://nextcloud.org/ns">`)
+	for _, r := range responses {
+		io.WriteString(w, r)
+	}
+	io.WriteString(w, "</d:multistatus>")
+}
+
+// patchProps answers a PROPPATCH, apply is called for every property to set or remove
+// and returns false when the property can not be changed
+func patchProps(w http.ResponseWriter, r *http.Request, href string, apply func(prop xmlNode, set bool) bool) {
+	var update xmlNode
+	if err := xml.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+
+	var propstats strings.Builder
+	for _, action := range update.Nodes {
+		prop, _ := action.child("prop")
+		for _, p := range prop.Nodes {
+			status := "HTTP/1.1 403 Forbidden"
+			if apply(p, action.XMLName.Local == "set") {
+				status = "HTTP/1.1 200 OK"
+			}
+			fmt.Fprintf(&propstats, `<d:propstat><d:prop><x:%s xmlns:x="%s"/></d:prop><d:status>%s</d:status></d:propstat>`,
+				p.XMLName.Local, escape(p.XMLName.Space), status)
+		}
+	}
+	writeMultistatus(w, []string{"<d:response><d:href>" + escape(href) + "</d:href>" + propstats.String() + "</d:response>"})
+}
+
+// writeError writes an error in the format of sabre/dav
+func writeError(w http.ResponseWriter, code int, exception, message string) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(code)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?>`+"\n"+
+		`<d:error xmlns:d="DAV:" xmlns:s="http://sabredav.org/ns"><s:exception>Sabre\DAV\Exception\%s</s:exception><s:message>%s</s:message></d:error>`,
+		exception, escape(message))
+}
@@ -0,0 +1,203 @@
+package davtest
+
+import (
+	"encoding/xml"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// xmlNode is a generic XML element of a search request
+type xmlNode struct {
+	XMLName xml.Name
+	Nodes   []xmlNode `xml:",any"`
+	Text    string    `xml:",chardata"`
+}
+
+func (n xmlNode) child(name string) (xmlNode, bool) {
+	for _, child := range n.Nodes {
+		if child.XMLName.Local == name {
+			return child, true
+		}
+	}
+	return xmlNode{}, false
+}
+
+// find follows the path of element names
+func (n xmlNode) find(names ...string) (xmlNode, bool) {
+	for _, name := range names {
+		var ok bool
+		if n, ok = n.child(name); !ok {
+			return n, false
+		}
+	}
+	return n, true
+}
+
+// serveSearch answers a SEARCH request like the DAV search of the server
+func (s *Server) serveSearch(w http.ResponseWriter, r *http.Request) {
+	var request xmlNode
+	if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	search, ok := request.child("basicsearch")
+	href, hasScope := search.find("from", "scope", "href")
+	where, hasWhere := search.child("where")
+	if !ok || !hasScope || !hasWhere || len(where.Nodes) != 1 {
+		writeError(w, http.StatusBadRequest, "BadRequest", "Invalid search request")
+		return
+	}
+
+	prefix := "/files/" + s.id()
+	scope := strings.TrimSpace(href.Text)
+	if scope != prefix && !strings.HasPrefix(scope, prefix+"/") {
+		writeError(w, http.StatusForbidden, "Forbidden", "Only the files of the user can be searched")
+		return
+	}
+	scope = clean(strings.TrimPrefix(scope, prefix))
+
+	var matches []string
+	for p := range s.nodes {
+		if p == scope || (scope != "/" && !strings.HasPrefix(p, scope+"/")) {
+			continue
+		}
+		if s.matches(p, where.Nodes[0]) {
+			matches = append(matches, p)
+		}
+	}
+	sort.Strings(matches)
+
+	if orderby, ok := search.child("orderby"); ok {
+		sort.SliceStable(matches, func(i, j int) bool {
+			for _, order := range orderby.Nodes {
+				prop, _ := order.find("prop")
+				if len(prop.Nodes) == 0 {
+					continue
+				}
+				c := compareValues(s.searchValue(matches[i], prop.Nodes[0].XMLName.Local), s.searchValue(matches[j], prop.Nodes[0].XMLName.Local))
+				if _, descending := order.child("descending"); descending {
+					c = -c
+				}
+				if c != 0 {
+					return c < 0
+				}
+			}
+			return false
+		})
+	}
+
+	if limit, ok := search.child("limit"); ok {
+		if first, ok := limit.child("firstresult"); ok {
+			offset, _ := strconv.Atoi(strings.TrimSpace(first.Text))
+			matches = matches[min(offset, len(matches)):]
+		}
+		if n, ok := limit.child("nresults"); ok {
+			count, _ := strconv.Atoi(strings.TrimSpace(n.Text))
+			matches = matches[:min(count, len(matches))]
+		}
+	}
+
+	responses := make([]string, len(matches))
+	for i, p := range matches {
+		responses[i] = response(s.filesRoot()+escapePath(p), s.nodes[p].dir, s.fileProps(p))
+	}
+	writeMultistatus(w, responses)
+}
+
+// matches evaluates the condition of a search for the file
+func (s *Server) matches(name string, condition xmlNode) bool {
+	switch condition.XMLName.Local {
+	case "and":
+		for _, c := range condition.Nodes {
+			if !s.matches(name, c) {
+				return false
+			}
+		}
+		return true
+	case "or":
+		for _, c := range condition.Nodes {
+			if s.matches(name, c) {
+				return true
+			}
+		}
+		return false
+	case "not":
+		return len(condition.Nodes) == 1 && !s.matches(name, condition.Nodes[0])
+	case "is-collection":
+		return s.nodes[name].dir
+	}
+
+	prop, ok := condition.find("prop")
+	literal, hasLiteral := condition.child("literal")
+	if !ok || !hasLiteral || len(prop.Nodes) == 0 {
+		return false
+	}
+	value := s.searchValue(name, prop.Nodes[0].XMLName.Local)
+
+	switch condition.XMLName.Local {
+	case "like":
+		pattern := regexp.QuoteMeta(literal.Text)
+		pattern = strings.NewReplacer("%", ".*", "_", ".").Replace(pattern)
+		ok, _ := regexp.MatchString("(?i)^"+pattern+"$", value)
+		return ok
+	case "eq":
+		return compareValues(value, literal.Text) == 0
+	case "gt":
+		return compareValues(value, literal.Text) > 0
+	case "gte":
+		return compareValues(value, literal.Text) >= 0
+	case "lt":
+		return compareValues(value, literal.Text) < 0
+	case "lte":
+		return compareValues(value, literal.Text) <= 0
+	}
+	return false
+}
+
+// searchValue returns the property of the file as it is compared by the search
+func (s *Server) searchValue(name, prop string) string {
+	n := s.nodes[name]
+	switch prop {
+	case "displayname":
+		return path.Base(name)
+	case "getcontenttype":
+		if n.dir {
+			return "httpd/unix-directory"
+		}
+		return contentType(name)
+	case "getlastmodified":
+		return strconv.FormatInt(n.modTime.Unix(), 10)
+	case "size":
+		return strconv.Itoa(s.size(name))
+	case "favorite":
+		if n.favorite {
+			return "1"
+		}
+		return "0"
+	case "owner-id":
+		return s.id()
+	case "fileid":
+		return strconv.FormatInt(n.id, 10)
+	}
+	return ""
+}
+
+// compareValues compares numbers by value and everything else as text
+func compareValues(a, b string) int {
+	x, errA := strconv.ParseInt(a, 10, 64)
+	y, errB := strconv.ParseInt(b, 10, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,217 @@
+package davtest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tag is a collaborative system tag
+type tag struct {
+	id         int64
+	name       string
+	visible    bool
+	assignable bool
+}
+
+// Tags returns the names of the system tags of a file or folder
+func (s *Server) Tags(name string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := []string{}
+	if n, ok := s.nodes[clean(name)]; ok {
+		for id := range n.tags {
+			names = append(names, s.tags[id].name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Server) tagProps(t *tag) string {
+	return fmt.Sprintf("<oc:id>%d</oc:id><oc:display-name>%s</oc:display-name>"+
+		"<oc:user-visible>%t</oc:user-visible><oc:user-assignable>%t</oc:user-assignable><oc:can-assign>true</oc:can-assign>",
+		t.id, escape(t.name), t.visible, t.assignable)
+}
+
+// sortedTags returns the tags with the given ids ordered by id
+func (s *Server) sortedTags(ids map[int64]bool) []*tag {
+	tags := []*tag{}
+	for id := range ids {
+		tags = append(tags, s.tags[id])
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].id < tags[j].id })
+	return tags
+}
+
+func (s *Server) serveSystemTags(w http.ResponseWriter, r *http.Request, name string) {
+	const root = "/remote.php/dav/systemtags"
+	if name == "/" {
+		switch r.Method {
+		case "PROPFIND":
+			ids := map[int64]bool{}
+			for id := range s.tags {
+				ids[id] = true
+			}
+			responses := []string{response(root, true, "<d:resourcetype><d:collection/></d:resourcetype>")}
+			for _, t := range s.sortedTags(ids) {
+				responses = append(responses, response(root+"/"+strconv.FormatInt(t.id, 10), false, s.tagProps(t)))
+			}
+			writeMultistatus(w, responses)
+		case http.MethodPost:
+			var body struct {
+				Name           string `json:"name"`
+				UserVisible    bool   `json:"userVisible"`
+				UserAssignable bool   `json:"userAssignable"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Name == "" {
+				writeError(w, http.StatusBadRequest, "BadRequest", "Missing tag name")
+				return
+			}
+			for _, t := range s.tags {
+				if t.name == body.Name {
+					writeError(w, http.StatusConflict, "Conflict", "Tag already exists")
+					return
+				}
+			}
+			if s.tags == nil {
+				s.tags = map[int64]*tag{}
+			}
+			s.lastTagID++
+			s.tags[s.lastTagID] = &tag{id: s.lastTagID, name: body.Name, visible: body.UserVisible, assignable: body.UserAssignable}
+			w.Header().Set("Content-Location", root+"/"+strconv.FormatInt(s.lastTagID, 10))
+			w.WriteHeader(http.StatusCreated)
+		default:
+			writeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" is not supported by davtest")
+		}
+		return
+	}
+
+	id, _ := strconv.ParseInt(strings.TrimPrefix(name, "/"), 10, 64)
+	t, ok := s.tags[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NotFound", "Tag with id "+strings.TrimPrefix(name, "/")+" not found")
+		return
+	}
+	switch r.Method {
+	case "PROPFIND":
+		writeMultistatus(w, []string{response(root+name, false, s.tagProps(t))})
+	case http.MethodDelete:
+		delete(s.tags, id)
+		for _, n := range s.nodes {
+			delete(n.tags, id)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" is not supported by davtest")
+	}
+}
+
+func (s *Server) serveTagRelations(w http.ResponseWriter, r *http.Request, name string) {
+	const root = "/remote.php/dav/systemtags-relations/files/"
+	parts := strings.Split(strings.TrimPrefix(name, "/"), "/")
+	if parts[0] != "files" || len(parts) < 2 || len(parts) > 3 {
+		writeError(w, http.StatusNotFound, "NotFound", "Node with name "+name+" could not be located")
+		return
+	}
+	_, n := s.nodeByID(parts[1])
+	if n == nil {
+		writeError(w, http.StatusNotFound, "NotFound", "File with id "+parts[1]+" not found")
+		return
+	}
+
+	if len(parts) == 2 {
+		if r.Method != "PROPFIND" {
+			writeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" is not supported by davtest")
+			return
+		}
+		responses := []string{response(root+parts[1], true, "<d:resourcetype><d:collection/></d:resourcetype>")}
+		for _, t := range s.sortedTags(n.tags) {
+			responses = append(responses, response(root+parts[1]+"/"+strconv.FormatInt(t.id, 10), false, s.tagProps(t)))
+		}
+		writeMultistatus(w, responses)
+		return
+	}
+
+	id, _ := strconv.ParseInt(parts[2], 10, 64)
+	if _, ok := s.tags[id]; !ok {
+		writeError(w, http.StatusNotFound, "NotFound", "Tag with id "+parts[2]+" not found")
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		if n.tags == nil {
+			n.tags = map[int64]bool{}
+		}
+		n.tags[id] = true
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		if !n.tags[id] {
+			writeError(w, http.StatusNotFound, "NotFound", "Tag "+parts[2]+" is not assigned to the file")
+			return
+		}
+		delete(n.tags, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" is not supported by davtest")
+	}
+}
+
+// proppatch changes the favorite, all other properties are rejected like read-only properties
+func (s *Server) proppatch(w http.ResponseWriter, r *http.Request, name string) {
+	patchProps(w, r, s.filesRoot()+escapePath(name), func(prop xmlNode, set bool) bool {
+		if prop.XMLName.Space != "http://owncloud.org/ns" || prop.XMLName.Local != "favorite" {
+			return false
+		}
+		s.nodes[name].favorite = set && strings.TrimSpace(prop.Text) == "1"
+		return true
+	})
+}
+
+// report answers oc:filter-files with the files below name that match all rules
+func (s *Server) report(w http.ResponseWriter, r *http.Request, name string) {
+	var filter xmlNode
+	if err := xml.NewDecoder(r.Body).Decode(&filter); err != nil || filter.XMLName.Local != "filter-files" {
+		writeError(w, http.StatusBadRequest, "BadRequest", "Only oc:filter-files reports are supported")
+		return
+	}
+	rules, _ := filter.child("filter-rules")
+	if len(rules.Nodes) == 0 {
+		writeError(w, http.StatusBadRequest, "BadRequest", "Missing filter-rule block in request")
+		return
+	}
+
+	var names []string
+	for p, n := range s.nodes {
+		if p == name || (name != "/" && !strings.HasPrefix(p, name+"/")) || !s.nodes[name].dir {
+			continue
+		}
+		matches := true
+		for _, rule := range rules.Nodes {
+			switch rule.XMLName.Local {
+			case "favorite":
+				matches = matches && n.favorite == (strings.TrimSpace(rule.Text) == "1")
+			case "systemtag":
+				id, _ := strconv.ParseInt(strings.TrimSpace(rule.Text), 10, 64)
+				matches = matches && n.tags[id]
+			default:
+				matches = false
+			}
+		}
+		if matches {
+			names = append(names, p)
+		}
+	}
+	sort.Strings(names)
+
+	responses := make([]string, len(names))
+	for i, p := range names {
+		responses[i] = response(s.filesRoot()+escapePath(p), s.nodes[p].dir, s.fileProps(p))
+	}
+	writeMultistatus(w, responses)
+}
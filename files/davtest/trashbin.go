@@ -0,0 +1,146 @@
+package davtest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// trashItem is a deleted file or folder with all its children keyed by their path below it
+type trashItem struct {
+	nodes            map[string]*node
+	originalLocation string
+	deletionTime     time.Time
+}
+
+func (s *Server) trashbinRoot() string {
+	return "/remote.php/dav/trashbin/" + s.id()
+}
+
+// Trash returns the names of the items in the trash bin
+func (s *Server) Trash() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return sortedKeys(s.trash)
+}
+
+// moveToTrash deletes the node and keeps it in the trash bin like the files_trashbin app
+func (s *Server) moveToTrash(name string) {
+	deleted := time.Now().UTC().Truncate(time.Second)
+	item := &trashItem{nodes: s.remove(name), originalLocation: name, deletionTime: deleted}
+
+	if s.trash == nil {
+		s.trash = map[string]*trashItem{}
+	}
+	timestamp := deleted.Unix()
+	for {
+		trashName := fmt.Sprintf("%s.d%d", path.Base(name), timestamp)
+		if _, exists := s.trash[trashName]; !exists {
+			s.trash[trashName] = item
+			return
+		}
+		timestamp++
+	}
+}
+
+func (s *Server) serveTrashbin(w http.ResponseWriter, r *http.Request, name string) {
+	parts := strings.Split(strings.TrimPrefix(name, "/"), "/")
+	if parts[0] != "trash" {
+		writeError(w, http.StatusNotFound, "NotFound", "Node with name "+name+" could not be located")
+		return
+	}
+
+	switch {
+	case r.Method == "PROPFIND" && len(parts) == 1:
+		root := s.trashbinRoot() + "/trash"
+		responses := []string{response(root, true, "<d:resourcetype><d:collection/></d:resourcetype>")}
+		if r.Header.Get("Depth") != "0" {
+			for _, trashName := range sortedKeys(s.trash) {
+				responses = append(responses, response(root+"/"+escapePath(trashName), s.trash[trashName].nodes[""].dir, s.trashProps(trashName)))
+			}
+		}
+		writeMultistatus(w, responses)
+	case r.Method == http.MethodDelete && len(parts) == 1:
+		s.trash = nil
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) != 2 || s.trash[parts[1]] == nil:
+		writeError(w, http.StatusNotFound, "NotFound", "File with name "+name+" could not be located")
+	case r.Method == "PROPFIND":
+		writeMultistatus(w, []string{response(s.trashbinRoot()+"/trash/"+escapePath(parts[1]), s.trash[parts[1]].nodes[""].dir, s.trashProps(parts[1]))})
+	case r.Method == http.MethodDelete:
+		delete(s.trash, parts[1])
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "MOVE":
+		u, err := url.Parse(r.Header.Get("Destination"))
+		if err != nil || !strings.HasPrefix(u.Path, s.trashbinRoot()+"/restore/") {
+			writeError(w, http.StatusBadRequest, "BadRequest", "Items can only be restored to the restore collection")
+			return
+		}
+		s.restore(parts[1])
+		w.WriteHeader(http.StatusCreated)
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" is not supported by davtest")
+	}
+}
+
+// restore moves the item back to its original location. Like the server it restores
+// to the root when the parent is gone and adds " (restored)" when the name is taken.
+func (s *Server) restore(trashName string) {
+	item := s.trash[trashName]
+	delete(s.trash, trashName)
+
+	target := item.originalLocation
+	if parent, ok := s.nodes[path.Dir(target)]; !ok || !parent.dir {
+		target = "/" + path.Base(target)
+	}
+	for {
+		if _, exists := s.nodes[target]; !exists {
+			break
+		}
+		ext := path.Ext(target)
+		target = strings.TrimSuffix(target, ext) + " (restored)" + ext
+	}
+
+	for p, n := range item.nodes {
+		s.nodes[target+p] = n
+	}
+	s.touch(target, time.Now())
+}
+
+func (s *Server) trashProps(trashName string) string {
+	item := s.trash[trashName]
+	n := item.nodes[""]
+	size := 0
+	for _, child := range item.nodes {
+		size += len(child.content)
+	}
+
+	props := fmt.Sprintf("<d:getetag>&quot;%s&quot;</d:getetag>"+
+		"<d:getlastmodified>%s</d:getlastmodified>"+
+		"<oc:fileid>%d</oc:fileid>"+
+		"<oc:size>%d</oc:size>"+
+		"<nc:trashbin-filename>%s</nc:trashbin-filename>"+
+		"<nc:trashbin-original-location>%s</nc:trashbin-original-location>"+
+		"<nc:trashbin-deletion-time>%d</nc:trashbin-deletion-time>",
+		n.etag, n.modTime.Format(http.TimeFormat), n.id, size,
+		escape(path.Base(item.originalLocation)), escape(strings.TrimPrefix(item.originalLocation, "/")), item.deletionTime.Unix())
+	if n.dir {
+		return props + "<d:resourcetype><d:collection/></d:resourcetype>"
+	}
+	return props + fmt.Sprintf("<d:resourcetype/><d:getcontentlength>%d</d:getcontentlength><d:getcontenttype>%s</d:getcontenttype>",
+		len(n.content), escape(contentType(item.originalLocation)))
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,151 @@
+package davtest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// upload is a chunked upload in progress below remote.php/dav/uploads/{user}
+type upload struct {
+	chunks map[string][]byte
+}
+
+// Uploads returns the ids of the chunked uploads that were started but not assembled yet
+func (s *Server) Uploads() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := []string{}
+	for id := range s.uploads {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (s *Server) uploadsRoot() string {
+	return "/remote.php/dav/uploads/" + s.id()
+}
+
+func (s *Server) serveUploads(w http.ResponseWriter, r *http.Request, name string) {
+	parts := strings.Split(strings.TrimPrefix(name, "/"), "/")
+	if parts[0] == "" {
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "Uploads need an id")
+		return
+	}
+	id := parts[0]
+	u, exists := s.uploads[id]
+
+	switch {
+	case r.Method == "MKCOL" && len(parts) == 1:
+		if exists {
+			writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "The resource you tried to create already exists")
+			return
+		}
+		if s.uploads == nil {
+			s.uploads = map[string]*upload{}
+		}
+		s.uploads[id] = &upload{chunks: map[string][]byte{}}
+		w.WriteHeader(http.StatusCreated)
+	case !exists:
+		writeError(w, http.StatusNotFound, "NotFound", "Upload "+id+" could not be located")
+	case r.Method == "PROPFIND" && len(parts) == 1:
+		names := []string{}
+		for chunk := range u.chunks {
+			names = append(names, chunk)
+		}
+		sort.Strings(names)
+
+		root := s.uploadsRoot() + "/" + escapePath(id)
+		responses := []string{response(root, true, "<d:resourcetype><d:collection/></d:resourcetype>")}
+		if r.Header.Get("Depth") != "0" {
+			for _, chunk := range names {
+				responses = append(responses, response(root+"/"+escapePath(chunk), false,
+					fmt.Sprintf("<d:resourcetype/><d:getcontentlength>%d</d:getcontentlength>", len(u.chunks[chunk]))))
+			}
+		}
+		writeMultistatus(w, responses)
+	case r.Method == http.MethodPut && len(parts) == 2:
+		n, err := strconv.Atoi(parts[1])
+		if err != nil || n < 1 || n > 10000 {
+			writeError(w, http.StatusBadRequest, "BadRequest", "Chunks must be numbered from 1 to 10000")
+			return
+		}
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "BadRequest", err.Error())
+			return
+		}
+		_, replaced := u.chunks[parts[1]]
+		u.chunks[parts[1]] = content
+		if replaced {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.WriteHeader(http.StatusCreated)
+		}
+	case r.Method == "MOVE" && len(parts) == 2 && parts[1] == ".file":
+		s.assemble(w, r, id, u)
+	case r.Method == http.MethodDelete && len(parts) == 1:
+		delete(s.uploads, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" is not supported by davtest")
+	}
+}
+
+// assemble joins the chunks in the order of their names and moves them to the destination
+func (s *Server) assemble(w http.ResponseWriter, r *http.Request, id string, u *upload) {
+	target, ok := s.destination(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "BadRequest", "Invalid destination")
+		return
+	}
+	if parent, ok := s.nodes[path.Dir(target)]; !ok || !parent.dir {
+		writeError(w, http.StatusConflict, "Conflict", "The destination node is not found")
+		return
+	}
+	if n, ok := s.nodes[target]; ok && n.dir {
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "Cannot overwrite a folder with a file")
+		return
+	}
+
+	names := make([]string, 0, len(u.chunks))
+	for chunk := range u.chunks {
+		names = append(names, chunk)
+	}
+	sort.Strings(names)
+
+	var content []byte
+	for _, chunk := range names {
+		content = append(content, u.chunks[chunk]...)
+	}
+	if total := r.Header.Get("OC-Total-Length"); total != "" && total != strconv.Itoa(len(content)) {
+		writeError(w, http.StatusBadRequest, "BadRequest", "Expected filesize of "+total+" bytes but read "+strconv.Itoa(len(content))+" bytes")
+		return
+	}
+
+	modTime := time.Now()
+	if mtime, err := strconv.ParseInt(r.Header.Get("X-OC-MTime"), 10, 64); err == nil {
+		modTime = time.Unix(mtime, 0)
+		w.Header().Set("X-OC-MTime", "accepted")
+	}
+
+	_, exists := s.nodes[target]
+	n := s.writeFile(target, content, modTime)
+	delete(s.uploads, id)
+
+	w.Header().Set("ETag", `"`+n.etag+`"`)
+	w.Header().Set("OC-ETag", `"`+n.etag+`"`)
+	w.Header().Set("OC-FileId", fileID(n.id))
+	if exists {
+		w.WriteHeader(http.StatusNoContent)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+}
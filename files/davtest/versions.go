@@ -0,0 +1,124 @@
+package davtest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// version is an older content of a file, named by its modification time like the files_versions app
+type version struct {
+	name    string
+	content []byte
+	modTime time.Time
+	etag    string
+}
+
+// addVersion keeps the current content of the file as version
+func (n *node) addVersion() {
+	timestamp := n.modTime.Unix()
+	for {
+		name := strconv.FormatInt(timestamp, 10)
+		if n.findVersion(name) == nil {
+			n.versions = append(n.versions, &version{name: name, content: n.content, modTime: n.modTime, etag: n.etag})
+			return
+		}
+		timestamp++
+	}
+}
+
+func (n *node) findVersion(name string) *version {
+	for _, v := range n.versions {
+		if v.name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// Versions returns the names of the versions of a file
+func (s *Server) Versions(name string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := []string{}
+	if n, ok := s.nodes[clean(name)]; ok {
+		for _, v := range n.versions {
+			names = append(names, v.name)
+		}
+	}
+	return names
+}
+
+func (s *Server) versionsRoot() string {
+	return "/remote.php/dav/versions/" + s.id()
+}
+
+// nodeByID returns the path and node of the file with the given id
+func (s *Server) nodeByID(id string) (string, *node) {
+	for p, n := range s.nodes {
+		if strconv.FormatInt(n.id, 10) == id {
+			return p, n
+		}
+	}
+	return "", nil
+}
+
+func (s *Server) serveVersions(w http.ResponseWriter, r *http.Request, name string) {
+	parts := strings.Split(strings.TrimPrefix(name, "/"), "/")
+	if parts[0] != "versions" || len(parts) < 2 || len(parts) > 3 {
+		writeError(w, http.StatusNotFound, "NotFound", "Node with name "+name+" could not be located")
+		return
+	}
+	p, n := s.nodeByID(parts[1])
+	if n == nil || n.dir {
+		writeError(w, http.StatusNotFound, "NotFound", "Versions for file "+parts[1]+" not found")
+		return
+	}
+
+	root := s.versionsRoot() + "/versions/" + parts[1]
+	if len(parts) == 2 {
+		if r.Method != "PROPFIND" {
+			writeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" is not supported by davtest")
+			return
+		}
+		responses := []string{response(root, true, "<d:resourcetype><d:collection/></d:resourcetype>")}
+		if r.Header.Get("Depth") != "0" {
+			for _, v := range n.versions {
+				responses = append(responses, response(root+"/"+v.name, false, fmt.Sprintf("<d:resourcetype/>"+
+					"<d:getetag>&quot;%s&quot;</d:getetag>"+
+					"<d:getlastmodified>%s</d:getlastmodified>"+
+					"<d:getcontentlength>%d</d:getcontentlength>"+
+					"<d:getcontenttype>%s</d:getcontenttype>"+
+					"<nc:version-label></nc:version-label>"+
+					"<nc:version-author>%s</nc:version-author>",
+					v.etag, v.modTime.Format(http.TimeFormat), len(v.content), escape(contentType(p)), escape(s.id()))))
+			}
+		}
+		writeMultistatus(w, responses)
+		return
+	}
+
+	v := n.findVersion(parts[2])
+	if v == nil {
+		writeError(w, http.StatusNotFound, "NotFound", "Version "+parts[2]+" not found")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", contentType(p))
+		w.Header().Set("Content-Length", strconv.Itoa(len(v.content)))
+		w.Write(v.content)
+	case "MOVE":
+		if !strings.HasPrefix(r.Header.Get("Destination"), s.URL+s.versionsRoot()+"/restore/") {
+			writeError(w, http.StatusBadRequest, "BadRequest", "Versions can only be restored to the restore collection")
+			return
+		}
+		s.writeFile(p, v.content, v.modTime)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" is not supported by davtest")
+	}
+}
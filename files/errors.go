@@ -0,0 +1,91 @@
+package files
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+)
+
+var (
+	// ErrNotFound when the file or folder does not exist
+	ErrNotFound = errors.New("File or folder does not exist")
+	// ErrAlreadyExists when the target of a new folder, move or copy already exists
+	ErrAlreadyExists = errors.New("File or folder already exists")
+	// ErrParentNotFound when the parent folder of the target does not exist
+	ErrParentNotFound = errors.New("Parent folder does not exist")
+	// ErrLocked when the file is locked by another request or user
+	ErrLocked = errors.New("File or folder is locked")
+	// ErrInsufficientStorage when the quota of the user does not allow the upload
+	ErrInsufficientStorage = errors.New("Not enough free space")
+	// ErrTagAlreadyExists when a system tag with the same name exists
+	ErrTagAlreadyExists = errors.New("Tag already exists")
+
+	// ErrUnauthorized when the login was not accepted by the server
+	ErrUnauthorized = errors.New("Unauthorized")
+	// ErrInsufficientPermissions when the logged in user is not allowed to perform the action
+	ErrInsufficientPermissions = errors.New("Insufficient permissions")
+)
+
+// Error is returned when the server rejected a WebDAV request
+type Error struct {
+	// Op describes the failed operation, e.g. "moving the file"
+	Op string
+	// Path is the path of the file the operation was performed on
+	Path string
+	// Err is the sentinel error of the status code, nil for unknown failures
+	Err error
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+	// Message is the reason the server gave, if any
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return "An error occured while " + e.Op
+}
+
+// Unwrap returns the sentinel error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// statusErrors maps the HTTP status codes of a method to sentinel errors
+type statusErrors map[int]error
+
+var commonErrors = statusErrors{
+	http.StatusUnauthorized:        ErrUnauthorized,
+	http.StatusForbidden:           ErrInsufficientPermissions,
+	http.StatusNotFound:            ErrNotFound,
+	http.StatusLocked:              ErrLocked,
+	http.StatusInsufficientStorage: ErrInsufficientStorage,
+}
+
+var (
+	mkdirErrors    = statusErrors{http.StatusMethodNotAllowed: ErrAlreadyExists, http.StatusConflict: ErrParentNotFound}
+	uploadErrors   = statusErrors{http.StatusConflict: ErrParentNotFound}
+	transferErrors = statusErrors{http.StatusConflict: ErrParentNotFound, http.StatusPreconditionFailed: ErrAlreadyExists}
+	tagErrors      = statusErrors{http.StatusConflict: ErrTagAlreadyExists}
+)
+
+// newError reads the sabre/dav error of the response
+func newError(op, path string, response *http.Response, codes statusErrors) error {
+	var body struct {
+		Message string `xml:"http://sabredav.org/ns message"`
+	}
+	xml.NewDecoder(io.LimitReader(response.Body, 1<<16)).Decode(&body)
+
+	e := &Error{Op: op, Path: path, StatusCode: response.StatusCode, Message: body.Message}
+	if e.Message == "" {
+		e.Message = http.StatusText(response.StatusCode)
+	}
+
+	e.Err = codes[response.StatusCode]
+	if e.Err == nil {
+		e.Err = commonErrors[response.StatusCode]
+	}
+	return e
+}
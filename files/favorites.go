@@ -0,0 +1,98 @@
+package files
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// SetFavorite marks or unmarks a file or folder as favorite of the logged in user
+// Returns ErrNotFound when it does not exist
+func (f *Files) SetFavorite(name string, favorite bool) error {
+	return f.SetFavoriteContext(context.Background(), name, favorite)
+}
+
+// SetFavoriteContext is like SetFavorite but aborts the request when ctx is done
+func (f *Files) SetFavoriteContext(ctx context.Context, name string, favorite bool) error {
+	value := "0"
+	if favorite {
+		value = "1"
+	}
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<d:propertyupdate xmlns:d="DAV:" xmlns:oc="http://owncloud.org/ns">
+	<d:set><d:prop><oc:favorite>` + value + `</oc:favorite></d:prop></d:set>
+</d:propertyupdate>`
+	user, err := f.userID(ctx)
+	if err != nil {
+		return err
+	}
+	return f.proppatch(ctx, "changing the favorite", fileURL(user, name), cleanPath(name), body)
+}
+
+// proppatch changes properties and reports the first property the server rejected
+func (f *Files) proppatch(ctx context.Context, op, url, name, body string) error {
+	header := http.Header{"Content-Type": {"application/xml; charset=utf-8"}}
+	response, err := f.do(ctx, op, "PROPPATCH", url, name, strings.NewReader(body), header, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	responses, _, err := parseMultistatus(response.Body, "")
+	if err != nil {
+		return err
+	}
+	for _, r := range responses {
+		for _, propstat := range r.Propstats {
+			// The status line looks like "HTTP/1.1 403 Forbidden"
+			fields := strings.Fields(propstat.Status)
+			if len(fields) < 2 || fields[1] == "200" {
+				continue
+			}
+			code, _ := strconv.Atoi(fields[1])
+			return &Error{Op: op, Path: name, Err: commonErrors[code], StatusCode: code, Message: http.StatusText(code)}
+		}
+	}
+	return nil
+}
+
+// ListFavorites returns the favorites of the logged in user in all folders
+func (f *Files) ListFavorites() ([]FileInfo, error) {
+	return f.ListFavoritesContext(context.Background())
+}
+
+// ListFavoritesContext is like ListFavorites but aborts the request when ctx is done
+func (f *Files) ListFavoritesContext(ctx context.Context) ([]FileInfo, error) {
+	return f.filterFiles(ctx, "listing the favorites", "<oc:favorite>1</oc:favorite>")
+}
+
+// filterFiles sends an oc:filter-files REPORT with the given rules for the whole home folder
+func (f *Files) filterFiles(ctx context.Context, op, rules string) ([]FileInfo, error) {
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<oc:filter-files xmlns:d="DAV:" xmlns:oc="http://owncloud.org/ns" xmlns:nc="http://nextcloud.org/ns">
+	<d:prop>` + fileProps + `</d:prop>
+	<oc:filter-rules>` + rules + `</oc:filter-rules>
+</oc:filter-files>`
+	user, err := f.userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{"Content-Type": {"application/xml; charset=utf-8"}}
+	response, err := f.do(ctx, op, "REPORT", fileURL(user, "/"), "/", strings.NewReader(body), header, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	responses, names, err := parseMultistatus(response.Body, root(user))
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]FileInfo, len(responses))
+	for i, r := range responses {
+		infos[i] = r.prop().fileInfo(names[i])
+	}
+	return infos, nil
+}
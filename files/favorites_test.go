@@ -0,0 +1,45 @@
+package files
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFavorites(t *testing.T) {
+	server, api := newTestFiles(t)
+	server.WriteFile("/Documents/contract.pdf", []byte("signed"))
+	server.WriteFile("/Documents/draft.pdf", []byte("draft"))
+	server.Mkdir("/Photos")
+
+	for _, name := range []string{"/Documents/contract.pdf", "/Photos"} {
+		if err := api.SetFavorite(name, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if info, _ := api.Stat("/Photos"); !info.Favorite {
+		t.Error("The folder should be a favorite")
+	}
+
+	favorites, err := api.ListFavorites()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names(favorites), []string{"/Documents/contract.pdf", "/Photos"}) {
+		t.Errorf("Unexpected favorites %v", names(favorites))
+	}
+	if !favorites[1].IsDir || favorites[0].Size != 6 {
+		t.Errorf("Unexpected file info %+v", favorites)
+	}
+
+	if err := api.SetFavorite("/Photos", false); err != nil {
+		t.Fatal(err)
+	}
+	if favorites, _ := api.ListFavorites(); !reflect.DeepEqual(names(favorites), []string{"/Documents/contract.pdf"}) {
+		t.Errorf("Unexpected favorites %v", names(favorites))
+	}
+
+	if err := api.SetFavorite("/missing.txt", true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,203 @@
+package files
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileInfo describes a file or folder as returned by PROPFIND
+type FileInfo struct {
+	// Path is relative to the root of the user's files and starts with a slash
+	Path  string
+	Name  string
+	IsDir bool
+	// ETag changes whenever the file or, for folders, anything below it changes
+	ETag   string
+	FileID int64
+	// Size is the size of the file or the total size of the folder contents
+	Size    int64
+	ModTime time.Time
+	// Permissions of the logged in user, a combination of
+	// S (shared), R (shareable), M (mounted), G (readable), D (deletable),
+	// N (renameable), V (moveable), W (writable), C (create file) and K (create folder)
+	Permissions string
+	ContentType string
+	Favorite    bool
+	// Owner is the user id of the owner, which differs from the user for shared files
+	Owner string
+}
+
+// HasPermission returns true when the permissions contain the given letter
+func (f FileInfo) HasPermission(permission byte) bool {
+	return strings.IndexByte(f.Permissions, permission) >= 0
+}
+
+// Depth controls how far a PROPFIND descends into folders
+type Depth string
+
+const (
+	// DepthZero returns only the requested file or folder
+	DepthZero Depth = "0"
+	// DepthOne also returns the direct children of a folder
+	DepthOne Depth = "1"
+	// DepthInfinity returns the whole tree, which the server may have disabled
+	DepthInfinity Depth = "infinity"
+)
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:oc="http://owncloud.org/ns" xmlns:nc="http://nextcloud.org/ns">
+	<d:prop>` + fileProps + `</d:prop>
+</d:propfind>`
+
+const fileProps = `
+		<d:getetag/>
+		<d:getlastmodified/>
+		<d:getcontentlength/>
+		<d:getcontenttype/>
+		<d:resourcetype/>
+		<oc:fileid/>
+		<oc:size/>
+		<oc:permissions/>
+		<oc:favorite/>
+		<oc:owner-id/>
+	`
+
+type multistatus struct {
+	Responses []davResponse `xml:"DAV: response"`
+}
+
+type davResponse struct {
+	Href      string `xml:"DAV: href"`
+	Propstats []struct {
+		Prop   davProp `xml:"DAV: prop"`
+		Status string  `xml:"DAV: status"`
+	} `xml:"DAV: propstat"`
+}
+
+// davProp holds all properties known to the client, missing ones stay empty
+type davProp struct {
+	ETag          string `xml:"DAV: getetag"`
+	LastModified  string `xml:"DAV: getlastmodified"`
+	ContentLength string `xml:"DAV: getcontentlength"`
+	ContentType   string `xml:"DAV: getcontenttype"`
+	ResourceType  struct {
+		Collection *struct{} `xml:"DAV: collection"`
+	} `xml:"DAV: resourcetype"`
+	FileID      string `xml:"http://owncloud.org/ns fileid"`
+	Size        string `xml:"http://owncloud.org/ns size"`
+	Permissions string `xml:"http://owncloud.org/ns permissions"`
+	Favorite    string `xml:"http://owncloud.org/ns favorite"`
+	Owner       string `xml:"http://owncloud.org/ns owner-id"`
+
+	TrashbinFilename         string `xml:"http://nextcloud.org/ns trashbin-filename"`
+	TrashbinOriginalLocation string `xml:"http://nextcloud.org/ns trashbin-original-location"`
+	TrashbinDeletionTime     string `xml:"http://nextcloud.org/ns trashbin-deletion-time"`
+
+	VersionLabel  string `xml:"http://nextcloud.org/ns version-label"`
+	VersionAuthor string `xml:"http://nextcloud.org/ns version-author"`
+
+	ID             string `xml:"http://owncloud.org/ns id"`
+	DisplayName    string `xml:"http://owncloud.org/ns display-name"`
+	UserVisible    string `xml:"http://owncloud.org/ns user-visible"`
+	UserAssignable string `xml:"http://owncloud.org/ns user-assignable"`
+	CanAssign      string `xml:"http://owncloud.org/ns can-assign"`
+
+	ParentID         string `xml:"http://owncloud.org/ns parentId"`
+	Message          string `xml:"http://owncloud.org/ns message"`
+	Verb             string `xml:"http://owncloud.org/ns verb"`
+	ActorType        string `xml:"http://owncloud.org/ns actorType"`
+	ActorID          string `xml:"http://owncloud.org/ns actorId"`
+	ActorDisplayName string `xml:"http://owncloud.org/ns actorDisplayName"`
+	CreationDateTime string `xml:"http://owncloud.org/ns creationDateTime"`
+	ObjectID         string `xml:"http://owncloud.org/ns objectId"`
+	IsUnread         string `xml:"http://owncloud.org/ns isUnread"`
+	Mentions         []struct {
+		Type        string `xml:"http://owncloud.org/ns mentionType"`
+		ID          string `xml:"http://owncloud.org/ns mentionId"`
+		DisplayName string `xml:"http://owncloud.org/ns mentionDisplayName"`
+	} `xml:"http://owncloud.org/ns mentions>mention"`
+}
+
+// prop merges the found properties of all propstats of the response
+func (r davResponse) prop() davProp {
+	var prop davProp
+	for _, propstat := range r.Propstats {
+		if strings.Contains(propstat.Status, " 200 ") {
+			prop = propstat.Prop
+		}
+	}
+	return prop
+}
+
+// fileInfo converts the properties of a file or folder at the given path
+func (p davProp) fileInfo(name string) FileInfo {
+	info := FileInfo{
+		Path:        name,
+		Name:        baseName(name),
+		IsDir:       p.ResourceType.Collection != nil,
+		ETag:        strings.Trim(p.ETag, `"`),
+		Permissions: p.Permissions,
+		ContentType: p.ContentType,
+		Favorite:    p.Favorite == "1",
+		Owner:       p.Owner,
+	}
+	info.FileID, _ = strconv.ParseInt(p.FileID, 10, 64)
+	info.ModTime, _ = http.ParseTime(p.LastModified)
+	if size, err := strconv.ParseInt(p.Size, 10, 64); err == nil {
+		info.Size = size
+	} else {
+		info.Size, _ = strconv.ParseInt(p.ContentLength, 10, 64)
+	}
+	return info
+}
+
+// parseMultistatus decodes a PROPFIND or REPORT response.
+// The paths of the results are relative to the given root.
+func parseMultistatus(body io.Reader, root string) ([]davResponse, []string, error) {
+	var ms multistatus
+	if err := xml.NewDecoder(body).Decode(&ms); err != nil {
+		return nil, nil, err
+	}
+
+	names := make([]string, len(ms.Responses))
+	for i, r := range ms.Responses {
+		names[i] = relativePath(r.Href, root)
+	}
+	return ms.Responses, names, nil
+}
+
+// relativePath returns the unescaped path of href below root
+func relativePath(href, root string) string {
+	if u, err := url.Parse(href); err == nil {
+		href = u.Path
+	}
+	if i := strings.Index(href, root); i >= 0 {
+		href = href[i+len(root):]
+	}
+	href = strings.TrimSuffix(href, "/")
+	if !strings.HasPrefix(href, "/") {
+		href = "/" + href
+	}
+	return href
+}
+
+// escapePath escapes every segment of the path, so it can be appended to a url
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func baseName(p string) string {
+	if p == "/" {
+		return ""
+	}
+	return p[strings.LastIndex(p, "/")+1:]
+}
@@ -0,0 +1,335 @@
+// Package files allows to list, transfer and organize the files of the logged in user via WebDAV.
+package files
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nextcloud/nextcloudgo"
+)
+
+const davRoot = "/remote.php/dav"
+
+// Files gives access to the files of the logged in user below remote.php/dav/files/{user}
+type Files struct {
+	nc nextcloudgo.NextcloudGo
+	// user is shared by the copies, so the user id is only requested once
+	user *userID
+}
+
+// userID caches the id of the logged in user, which differs from the login name e.g. for LDAP or email logins
+type userID struct {
+	mu sync.Mutex
+	id string
+}
+
+// New returns a new Files instance when given the NextcloudGo
+func New(nc nextcloudgo.NextcloudGo) Files {
+	return Files{nc: nc, user: &userID{}}
+}
+
+const principalPropfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:">
+	<d:prop><d:current-user-principal/></d:prop>
+</d:propfind>`
+
+// userID returns the id of the logged in user that the DAV urls contain.
+// It is read from the current user principal on the first call.
+func (f *Files) userID(ctx context.Context) (string, error) {
+	cache := f.user
+	if cache == nil {
+		cache = &userID{}
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.id != "" {
+		return cache.id, nil
+	}
+
+	header := http.Header{"Depth": {string(DepthZero)}, "Content-Type": {"application/xml; charset=utf-8"}}
+	response, err := f.do(ctx, "resolving the user", "PROPFIND", davRoot+"/", "/", strings.NewReader(principalPropfindBody), header, nil)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	var body struct {
+		Href string `xml:"response>propstat>prop>current-user-principal>href"`
+	}
+	if err := xml.NewDecoder(response.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	id := relativePath(body.Href, davRoot+"/principals/users")
+	if id == "/" || strings.Contains(id[1:], "/") {
+		return "", errors.New("No user in the current user principal")
+	}
+	cache.id = id[1:]
+	return cache.id, nil
+}
+
+// root is the unescaped path of the user's files below the server url
+func root(user string) string {
+	return davRoot + "/files/" + user
+}
+
+// fileURL is the escaped url of the file below the server url
+func fileURL(user, name string) string {
+	return escapePath(root(user) + cleanPath(name))
+}
+
+func cleanPath(name string) string {
+	return path.Clean("/" + name)
+}
+
+// do performs the request and turns unsuccessful responses into an *Error
+func (f *Files) do(ctx context.Context, op, method, url, name string, body io.Reader, header http.Header, codes statusErrors) (*http.Response, error) {
+	response, err := f.nc.DAVRequestContext(ctx, method, url, body, header)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode >= 300 {
+		defer response.Body.Close()
+		return nil, newError(op, name, response, codes)
+	}
+	return response, nil
+}
+
+// propfind returns the responses and their paths below root
+func (f *Files) propfind(ctx context.Context, op, url, name, root, body string, depth Depth) ([]davResponse, []string, error) {
+	header := http.Header{"Depth": {string(depth)}, "Content-Type": {"application/xml; charset=utf-8"}}
+	response, err := f.do(ctx, op, "PROPFIND", url, name, strings.NewReader(body), header, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer response.Body.Close()
+
+	return parseMultistatus(response.Body, root)
+}
+
+// Propfind returns the file or folder at the given path and, depending on depth, its descendants
+func (f *Files) Propfind(name string, depth Depth) ([]FileInfo, error) {
+	return f.PropfindContext(context.Background(), name, depth)
+}
+
+// PropfindContext is like Propfind but aborts the request when ctx is done
+func (f *Files) PropfindContext(ctx context.Context, name string, depth Depth) ([]FileInfo, error) {
+	user, err := f.userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	responses, names, err := f.propfind(ctx, "listing the folder", fileURL(user, name), cleanPath(name), root(user), propfindBody, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]FileInfo, len(responses))
+	for i, r := range responses {
+		infos[i] = r.prop().fileInfo(names[i])
+	}
+	return infos, nil
+}
+
+// Stat returns the details of a file or folder
+// Returns ErrNotFound when it does not exist
+func (f *Files) Stat(name string) (FileInfo, error) {
+	return f.StatContext(context.Background(), name)
+}
+
+// StatContext is like Stat but aborts the request when ctx is done
+func (f *Files) StatContext(ctx context.Context, name string) (FileInfo, error) {
+	infos, err := f.PropfindContext(ctx, name, DepthZero)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if len(infos) == 0 {
+		return FileInfo{}, errors.New("File not found in response")
+	}
+	return infos[0], nil
+}
+
+// List returns the direct children of a folder
+// Returns ErrNotFound when the folder does not exist
+func (f *Files) List(name string) ([]FileInfo, error) {
+	return f.ListContext(context.Background(), name)
+}
+
+// ListContext is like List but aborts the request when ctx is done
+func (f *Files) ListContext(ctx context.Context, name string) ([]FileInfo, error) {
+	infos, err := f.PropfindContext(ctx, name, DepthOne)
+	if err != nil {
+		return nil, err
+	}
+
+	children := make([]FileInfo, 0, len(infos))
+	for _, info := range infos {
+		if info.Path != cleanPath(name) {
+			children = append(children, info)
+		}
+	}
+	return children, nil
+}
+
+// Download returns the contents of a file, the caller has to close the reader
+// Returns ErrNotFound when the file does not exist
+func (f *Files) Download(name string) (io.ReadCloser, error) {
+	return f.DownloadContext(context.Background(), name)
+}
+
+// DownloadContext is like Download but aborts the request when ctx is done
+func (f *Files) DownloadContext(ctx context.Context, name string) (io.ReadCloser, error) {
+	user, err := f.userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	response, err := f.do(ctx, "downloading the file", http.MethodGet, fileURL(user, name), cleanPath(name), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+// Upload creates or replaces a file with the contents of r, which is streamed to the server
+// Returns ErrParentNotFound when the parent folder does not exist
+func (f *Files) Upload(name string, r io.Reader) error {
+	return f.UploadContext(context.Background(), name, r)
+}
+
+// UploadContext is like Upload but aborts the request when ctx is done
+func (f *Files) UploadContext(ctx context.Context, name string, r io.Reader) error {
+	return f.UploadWithModTimeContext(ctx, name, r, time.Time{})
+}
+
+// UploadWithModTime is like Upload but sets the modification time of the file,
+// which is the time of the upload otherwise. Only whole seconds are kept by the server.
+func (f *Files) UploadWithModTime(name string, r io.Reader, modTime time.Time) error {
+	return f.UploadWithModTimeContext(context.Background(), name, r, modTime)
+}
+
+// UploadWithModTimeContext is like UploadWithModTime but aborts the request when ctx is done
+func (f *Files) UploadWithModTimeContext(ctx context.Context, name string, r io.Reader, modTime time.Time) error {
+	header := http.Header{"Content-Type": {"application/octet-stream"}}
+	if file, ok := r.(interface{ Stat() (fs.FileInfo, error) }); ok {
+		if info, err := file.Stat(); err == nil && info.Mode().IsRegular() {
+			header.Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+		}
+	}
+	if !modTime.IsZero() {
+		header.Set("X-OC-MTime", strconv.FormatInt(modTime.Unix(), 10))
+	}
+
+	user, err := f.userID(ctx)
+	if err != nil {
+		return err
+	}
+	response, err := f.do(ctx, "uploading the file", http.MethodPut, fileURL(user, name), cleanPath(name), r, header, uploadErrors)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// Mkdir creates a folder
+// Returns ErrAlreadyExists when it exists and ErrParentNotFound when the parent folder does not exist
+func (f *Files) Mkdir(name string) error {
+	return f.MkdirContext(context.Background(), name)
+}
+
+// MkdirContext is like Mkdir but aborts the request when ctx is done
+func (f *Files) MkdirContext(ctx context.Context, name string) error {
+	user, err := f.userID(ctx)
+	if err != nil {
+		return err
+	}
+	response, err := f.do(ctx, "creating the folder", "MKCOL", fileURL(user, name), cleanPath(name), nil, nil, mkdirErrors)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// MkdirAll creates a folder and all missing parents, existing folders are not an error
+func (f *Files) MkdirAll(name string) error {
+	return f.MkdirAllContext(context.Background(), name)
+}
+
+// MkdirAllContext is like MkdirAll but aborts the requests when ctx is done
+func (f *Files) MkdirAllContext(ctx context.Context, name string) error {
+	current := ""
+	for _, segment := range strings.Split(strings.Trim(cleanPath(name), "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		current += "/" + segment
+		if err := f.MkdirContext(ctx, current); err != nil && !errors.Is(err, ErrAlreadyExists) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Move renames a file or folder, an existing target is only replaced when overwrite is true
+// Returns ErrAlreadyExists when the target exists and overwrite is false
+func (f *Files) Move(source, target string, overwrite bool) error {
+	return f.MoveContext(context.Background(), source, target, overwrite)
+}
+
+// MoveContext is like Move but aborts the request when ctx is done
+func (f *Files) MoveContext(ctx context.Context, source, target string, overwrite bool) error {
+	return f.transfer(ctx, "moving the file", "MOVE", source, target, overwrite)
+}
+
+// Copy copies a file or folder, an existing target is only replaced when overwrite is true
+// Returns ErrAlreadyExists when the target exists and overwrite is false
+func (f *Files) Copy(source, target string, overwrite bool) error {
+	return f.CopyContext(context.Background(), source, target, overwrite)
+}
+
+// CopyContext is like Copy but aborts the request when ctx is done
+func (f *Files) CopyContext(ctx context.Context, source, target string, overwrite bool) error {
+	return f.transfer(ctx, "copying the file", "COPY", source, target, overwrite)
+}
+
+func (f *Files) transfer(ctx context.Context, op, method, source, target string, overwrite bool) error {
+	user, err := f.userID(ctx)
+	if err != nil {
+		return err
+	}
+	header := http.Header{"Destination": {f.nc.ServerURL + fileURL(user, target)}, "Overwrite": {"F"}}
+	if overwrite {
+		header.Set("Overwrite", "T")
+	}
+
+	response, err := f.do(ctx, op, method, fileURL(user, source), cleanPath(source), nil, header, transferErrors)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// Delete removes a file or folder with all its contents
+// Returns ErrNotFound when it does not exist
+func (f *Files) Delete(name string) error {
+	return f.DeleteContext(context.Background(), name)
+}
+
+// DeleteContext is like Delete but aborts the request when ctx is done
+func (f *Files) DeleteContext(ctx context.Context, name string) error {
+	user, err := f.userID(ctx)
+	if err != nil {
+		return err
+	}
+	response, err := f.do(ctx, "deleting the file", http.MethodDelete, fileURL(user, name), cleanPath(name), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
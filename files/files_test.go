@@ -0,0 +1,194 @@
+package files
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nextcloud/nextcloudgo"
+	"github.com/nextcloud/nextcloudgo/files/davtest"
+)
+
+func newTestFiles(t *testing.T) (*davtest.Server, Files) {
+	t.Helper()
+	server := davtest.NewServer("alice", "secret")
+	t.Cleanup(server.Close)
+
+	nc, err := nextcloudgo.New(server.URL, "alice", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, New(nc)
+}
+
+func names(infos []FileInfo) []string {
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Path)
+	}
+	return names
+}
+
+func TestPropfind(t *testing.T) {
+	server, api := newTestFiles(t)
+	server.WriteFile("/Documents/report 2024.pdf", []byte("# Report"))
+	server.WriteFile("/Documents/Archive/old.txt", []byte("old"))
+	server.WriteFile("/Photos/cat.jpg", []byte("meow"))
+	server.SetFavorite("/Photos", true)
+
+	info, err := api.Stat("/Documents/report 2024.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "report 2024.pdf" || info.IsDir || info.Size != 8 || info.ContentType != "application/pdf" {
+		t.Errorf("Unexpected file info %+v", info)
+	}
+	if info.ETag == "" || strings.Contains(info.ETag, `"`) || info.FileID != server.FileID("/Documents/report 2024.pdf") || info.ModTime.IsZero() {
+		t.Errorf("Unexpected file info %+v", info)
+	}
+	if !info.HasPermission('W') || info.HasPermission('C') {
+		t.Errorf("Unexpected permissions %q", info.Permissions)
+	}
+
+	children, err := api.List("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names(children), []string{"/Documents", "/Photos"}) {
+		t.Errorf("Unexpected children %v", names(children))
+	}
+	if !children[1].IsDir || !children[1].Favorite || children[1].Size != 4 {
+		t.Errorf("Unexpected folder info %+v", children[1])
+	}
+
+	tree, err := api.Propfind("/Documents", DepthInfinity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"/Documents", "/Documents/Archive", "/Documents/Archive/old.txt", "/Documents/report 2024.pdf"}
+	if !reflect.DeepEqual(names(tree), expected) {
+		t.Errorf("Unexpected tree %v", names(tree))
+	}
+
+	if _, err := api.Stat("/missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	server, api := newTestFiles(t)
+
+	if err := api.Upload("/Notes/todo.txt", strings.NewReader("milk")); !errors.Is(err, ErrParentNotFound) {
+		t.Errorf("Expected ErrParentNotFound, got %v", err)
+	}
+	if err := api.MkdirAll("/Notes/2024"); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.Mkdir("/Notes"); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("Expected ErrAlreadyExists, got %v", err)
+	}
+	if err := api.Upload("/Notes/todo.txt", strings.NewReader("milk")); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := api.Download("/Notes/todo.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(content) != "milk" {
+		t.Errorf("Unexpected content %q", content)
+	}
+
+	if err := api.Copy("/Notes", "/Backup", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.Move("/Notes/todo.txt", "/Backup/todo.txt", false); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("Expected ErrAlreadyExists, got %v", err)
+	}
+	if err := api.Move("/Notes/todo.txt", "/Backup/todo.txt", true); err != nil {
+		t.Fatal(err)
+	}
+	if server.Exists("/Notes/todo.txt") || !server.Exists("/Backup/2024") {
+		t.Error("Files were not moved or copied")
+	}
+
+	if err := api.Delete("/Backup"); err != nil {
+		t.Fatal(err)
+	}
+	var e *Error
+	if err := api.Delete("/Backup"); !errors.As(err, &e) || e.StatusCode != 404 || e.Path != "/Backup" || e.Message == "" {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	server, _ := newTestFiles(t)
+	nc, _ := nextcloudgo.New(server.URL, "alice", "wrong")
+	api := New(nc)
+
+	if _, err := api.List("/"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized, got %v", err)
+	}
+}
+
+// principalCounter counts the requests for the user id
+type principalCounter struct {
+	requests int
+}
+
+func (c *principalCounter) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Method == "PROPFIND" && r.URL.Path == "/remote.php/dav/" {
+		c.requests++
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestUserIDDiffersFromLogin(t *testing.T) {
+	server := davtest.NewServer("alice@example.com", "secret")
+	defer server.Close()
+	server.ID = "6a1f0c2e-alice"
+
+	counter := &principalCounter{}
+	nc, err := nextcloudgo.New(server.URL, "alice@example.com", "secret", nextcloudgo.WithTransport(counter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := New(nc)
+
+	if err := api.MkdirAll("/Notes"); err != nil {
+		t.Fatal(err)
+	}
+	for _, content := range []string{"milk", "milk and eggs"} {
+		if err := api.Upload("/Notes/todo.txt", strings.NewReader(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	infos, err := api.List("/Notes")
+	if err != nil || !reflect.DeepEqual(names(infos), []string{"/Notes/todo.txt"}) || infos[0].Owner != "6a1f0c2e-alice" {
+		t.Errorf("Unexpected files %+v %v", infos, err)
+	}
+	found, err := api.Search(NewQuery("/Notes"))
+	if err != nil || !reflect.DeepEqual(names(found), []string{"/Notes/todo.txt"}) {
+		t.Errorf("Unexpected results %v %v", names(found), err)
+	}
+	if versions, err := api.ListVersionsByPath("/Notes/todo.txt"); err != nil || len(versions) != 1 {
+		t.Errorf("Unexpected versions %+v %v", versions, err)
+	}
+
+	// Copies share the resolved user id
+	other := api
+	if err := other.Delete("/Notes/todo.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if items, err := other.ListTrash(); err != nil || len(items) != 1 {
+		t.Errorf("Unexpected trash %+v %v", items, err)
+	}
+	if counter.requests != 1 {
+		t.Errorf("The user id should be requested once, got %d requests", counter.requests)
+	}
+}
@@ -0,0 +1,251 @@
+package files
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SearchField is a property files can be filtered and ordered by
+type SearchField string
+
+// Fields supported by the search of the server
+const (
+	FieldName        SearchField = "d:displayname"
+	FieldContentType SearchField = "d:getcontenttype"
+	FieldModTime     SearchField = "d:getlastmodified"
+	FieldSize        SearchField = "oc:size"
+	FieldFavorite    SearchField = "oc:favorite"
+	FieldOwner       SearchField = "oc:owner-id"
+	FieldFileID      SearchField = "oc:fileid"
+)
+
+// Condition is a filter of a Query, use the functions below to create one
+type Condition struct {
+	xml string
+}
+
+func compare(operator string, field SearchField, literal string) Condition {
+	return Condition{"<d:" + operator + "><d:prop><" + string(field) + "/></d:prop><d:literal>" + escapeXML(literal) + "</d:literal></d:" + operator + ">"}
+}
+
+// likePattern turns the wildcards * and ? of a glob into the SQL-like pattern of the server
+func likePattern(pattern string) string {
+	return strings.NewReplacer("*", "%", "?", "_").Replace(pattern)
+}
+
+// Equal matches files whose field has exactly the given value
+func Equal(field SearchField, value string) Condition {
+	return compare("eq", field, value)
+}
+
+// Like matches files whose field matches the pattern, where * matches any text and ? a single character
+func Like(field SearchField, pattern string) Condition {
+	return compare("like", field, likePattern(pattern))
+}
+
+// MimeType matches files of the given content type, which may contain wildcards like "image/*"
+func MimeType(pattern string) Condition {
+	return Like(FieldContentType, pattern)
+}
+
+// NameLike matches files whose name matches the pattern, e.g. "*.pdf"
+func NameLike(pattern string) Condition {
+	return Like(FieldName, pattern)
+}
+
+// SizeGreaterThan matches files larger than the given number of bytes
+func SizeGreaterThan(bytes int64) Condition {
+	return compare("gt", FieldSize, strconv.FormatInt(bytes, 10))
+}
+
+// SizeLessThan matches files smaller than the given number of bytes
+func SizeLessThan(bytes int64) Condition {
+	return compare("lt", FieldSize, strconv.FormatInt(bytes, 10))
+}
+
+// ModifiedAfter matches files that were changed after t
+func ModifiedAfter(t time.Time) Condition {
+	return compare("gt", FieldModTime, strconv.FormatInt(t.Unix(), 10))
+}
+
+// ModifiedBefore matches files that were changed before t
+func ModifiedBefore(t time.Time) Condition {
+	return compare("lt", FieldModTime, strconv.FormatInt(t.Unix(), 10))
+}
+
+// Owner matches files owned by the given user id
+func Owner(userID string) Condition {
+	return Equal(FieldOwner, userID)
+}
+
+// Favorite matches the favorites of the user
+func Favorite() Condition {
+	return Equal(FieldFavorite, "1")
+}
+
+// IsFolder matches folders only
+func IsFolder() Condition {
+	return Condition{"<d:is-collection/>"}
+}
+
+// And matches files that match all conditions
+func And(conditions ...Condition) Condition {
+	return combine("and", conditions)
+}
+
+// Or matches files that match any of the conditions
+func Or(conditions ...Condition) Condition {
+	return combine("or", conditions)
+}
+
+// Not matches files that do not match the condition
+func Not(condition Condition) Condition {
+	return Condition{"<d:not>" + condition.xml + "</d:not>"}
+}
+
+func combine(operator string, conditions []Condition) Condition {
+	if len(conditions) == 1 {
+		return conditions[0]
+	}
+	var b strings.Builder
+	b.WriteString("<d:" + operator + ">")
+	for _, c := range conditions {
+		b.WriteString(c.xml)
+	}
+	b.WriteString("</d:" + operator + ">")
+	return Condition{b.String()}
+}
+
+type searchOrder struct {
+	field      SearchField
+	descending bool
+}
+
+// Query is a search for files below a folder. The methods return the query,
+// so they can be chained:
+//
+//	files.NewQuery("/").Where(files.MimeType("application/pdf"), files.SizeGreaterThan(1<<30)).OrderBy(files.FieldSize, true).Limit(50)
+type Query struct {
+	scope      string
+	conditions []Condition
+	orders     []searchOrder
+	limit      int
+	offset     int
+}
+
+// NewQuery returns a query for all files below the given folder
+func NewQuery(scope string) *Query {
+	return &Query{scope: cleanPath(scope)}
+}
+
+// Where adds conditions, which all have to match
+func (q *Query) Where(conditions ...Condition) *Query {
+	q.conditions = append(q.conditions, conditions...)
+	return q
+}
+
+// OrderBy sorts the results by the field, later calls are used when the earlier fields are equal
+func (q *Query) OrderBy(field SearchField, descending bool) *Query {
+	q.orders = append(q.orders, searchOrder{field, descending})
+	return q
+}
+
+// Limit returns at most n results, all when n is 0
+func (q *Query) Limit(n int) *Query {
+	q.limit = n
+	return q
+}
+
+// Offset skips the first n results, it needs Nextcloud 21
+func (q *Query) Offset(n int) *Query {
+	q.offset = n
+	return q
+}
+
+// XML returns the d:searchrequest for the files of the user with the given id
+func (q *Query) XML(user string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<d:searchrequest xmlns:d="DAV:" xmlns:oc="http://owncloud.org/ns" xmlns:nc="http://nextcloud.org/ns" xmlns:ns="https://github.com/icewind1991/SearchDAV/ns">
+	<d:basicsearch>
+		<d:select>
+			<d:prop>` + fileProps + `	</d:prop>
+		</d:select>
+		<d:from>
+			<d:scope>
+				<d:href>/files/` + escapeXML(user+q.scope) + `</d:href>
+				<d:depth>infinity</d:depth>
+			</d:scope>
+		</d:from>
+		<d:where>`)
+	if len(q.conditions) == 0 {
+		// The server requires a condition, every file has a size
+		b.WriteString(compare("gte", FieldSize, "0").xml)
+	} else {
+		b.WriteString(And(q.conditions...).xml)
+	}
+	b.WriteString(`</d:where>
+		<d:orderby>`)
+	for _, order := range q.orders {
+		direction := "<d:ascending/>"
+		if order.descending {
+			direction = "<d:descending/>"
+		}
+		b.WriteString("<d:order><d:prop><" + string(order.field) + "/></d:prop>" + direction + "</d:order>")
+	}
+	b.WriteString(`</d:orderby>`)
+	if q.limit > 0 || q.offset > 0 {
+		b.WriteString("\n\t\t<d:limit>")
+		if q.limit > 0 {
+			b.WriteString("<d:nresults>" + strconv.Itoa(q.limit) + "</d:nresults>")
+		}
+		if q.offset > 0 {
+			b.WriteString("<ns:firstresult>" + strconv.Itoa(q.offset) + "</ns:firstresult>")
+		}
+		b.WriteString("</d:limit>")
+	}
+	b.WriteString(`
+	</d:basicsearch>
+</d:searchrequest>`)
+	return b.String()
+}
+
+// Search returns the files matching the query
+func (f *Files) Search(q *Query) ([]FileInfo, error) {
+	return f.SearchContext(context.Background(), q)
+}
+
+// SearchContext is like Search but aborts the requests when ctx is done
+func (f *Files) SearchContext(ctx context.Context, q *Query) ([]FileInfo, error) {
+	user, err := f.userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{"Content-Type": {"text/xml; charset=utf-8"}}
+	response, err := f.do(ctx, "searching the files", "SEARCH", davRoot+"/", q.scope, strings.NewReader(q.XML(user)), header, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	responses, names, err := parseMultistatus(response.Body, root(user))
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]FileInfo, len(responses))
+	for i, r := range responses {
+		infos[i] = r.prop().fileInfo(names[i])
+	}
+	return infos, nil
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
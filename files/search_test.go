@@ -0,0 +1,65 @@
+package files
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryXML(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	q := NewQuery("/Documents").
+		Where(MimeType("application/*"), Or(NameLike("report*"), Favorite()), ModifiedAfter(since)).
+		OrderBy(FieldSize, true).
+		Limit(10).
+		Offset(20)
+
+	request := q.XML("alice")
+	for _, expected := range []string{
+		"<d:href>/files/alice/Documents</d:href>",
+		"<d:and><d:like><d:prop><d:getcontenttype/></d:prop><d:literal>application/%</d:literal></d:like>",
+		"<d:or><d:like><d:prop><d:displayname/></d:prop><d:literal>report%</d:literal></d:like><d:eq><d:prop><oc:favorite/></d:prop><d:literal>1</d:literal></d:eq></d:or>",
+		"<d:gt><d:prop><d:getlastmodified/></d:prop><d:literal>1704067200</d:literal></d:gt></d:and>",
+		"<d:order><d:prop><oc:size/></d:prop><d:descending/></d:order>",
+		"<d:limit><d:nresults>10</d:nresults><ns:firstresult>20</ns:firstresult></d:limit>",
+	} {
+		if !strings.Contains(request, expected) {
+			t.Errorf("Expected %s in\n%s", expected, request)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	server, api := newTestFiles(t)
+	server.WriteFile("/Documents/manual.pdf", []byte(strings.Repeat("m", 300)))
+	server.WriteFile("/Documents/Archive/scan.pdf", []byte(strings.Repeat("s", 200)))
+	server.WriteFile("/Documents/notes.txt", []byte("notes"))
+	server.WriteFile("/Photos/cover.pdf", []byte(strings.Repeat("c", 500)))
+	server.WriteFile("/Photos/cat.jpg", []byte("meow"))
+	server.SetFavorite("/Photos/cat.jpg", true)
+
+	results, err := api.Search(NewQuery("/").Where(MimeType("application/pdf"), SizeGreaterThan(100)).OrderBy(FieldSize, true).Limit(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names(results), []string{"/Photos/cover.pdf", "/Documents/manual.pdf"}) {
+		t.Errorf("Unexpected results %v", names(results))
+	}
+	if results[0].Size != 500 || results[0].Owner != "alice" || results[0].FileID == 0 {
+		t.Errorf("Unexpected file info %+v", results[0])
+	}
+
+	results, err = api.Search(NewQuery("/Documents").Where(Not(IsFolder()), NameLike("*.pdf")).OrderBy(FieldName, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names(results), []string{"/Documents/manual.pdf", "/Documents/Archive/scan.pdf"}) {
+		t.Errorf("Unexpected results %v", names(results))
+	}
+
+	results, err = api.Search(NewQuery("/").Where(Favorite(), Owner("alice")))
+	if err != nil || !reflect.DeepEqual(names(results), []string{"/Photos/cat.jpg"}) {
+		t.Errorf("Unexpected results %v %v", names(results), err)
+	}
+}
@@ -0,0 +1,188 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const tagsPropfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:oc="http://owncloud.org/ns">
+	<d:prop>
+		<oc:id/>
+		<oc:display-name/>
+		<oc:user-visible/>
+		<oc:user-assignable/>
+		<oc:can-assign/>
+	</d:prop>
+</d:propfind>`
+
+// SystemTag is a collaborative tag, which is shared by all users of the server
+type SystemTag struct {
+	ID   int64
+	Name string
+	// UserVisible tags are shown to all users, the others only to admins
+	UserVisible bool
+	// UserAssignable tags can be assigned by all users, the others only by admins
+	UserAssignable bool
+	// CanAssign is true when the logged in user may assign the tag
+	CanAssign bool
+}
+
+func (p davProp) systemTag() SystemTag {
+	tag := SystemTag{
+		Name:           p.DisplayName,
+		UserVisible:    p.UserVisible == "true",
+		UserAssignable: p.UserAssignable == "true",
+		CanAssign:      p.CanAssign == "true",
+	}
+	tag.ID, _ = strconv.ParseInt(p.ID, 10, 64)
+	return tag
+}
+
+func idPath(id int64) string {
+	return "/" + strconv.FormatInt(id, 10)
+}
+
+func relationsURL(fileID int64) string {
+	return davRoot + "/systemtags-relations/files/" + strconv.FormatInt(fileID, 10)
+}
+
+// listTags returns the tags of the collection, which is listed as first response
+func (f *Files) listTags(ctx context.Context, op, url, name string) ([]SystemTag, error) {
+	responses, _, err := f.propfind(ctx, op, url, name, "", tagsPropfindBody, DepthOne)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]SystemTag, 0, len(responses))
+	for _, r := range responses {
+		if tag := r.prop().systemTag(); tag.ID != 0 {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// ListTags returns the system tags visible to the logged in user
+func (f *Files) ListTags() ([]SystemTag, error) {
+	return f.ListTagsContext(context.Background())
+}
+
+// ListTagsContext is like ListTags but aborts the request when ctx is done
+func (f *Files) ListTagsContext(ctx context.Context) ([]SystemTag, error) {
+	return f.listTags(ctx, "listing the tags", davRoot+"/systemtags", "/")
+}
+
+// CreateTag adds a new system tag, only admins may create tags that are not visible or assignable
+// Returns ErrTagAlreadyExists when a tag with the same name exists
+func (f *Files) CreateTag(name string, userVisible, userAssignable bool) (SystemTag, error) {
+	return f.CreateTagContext(context.Background(), name, userVisible, userAssignable)
+}
+
+// CreateTagContext is like CreateTag but aborts the request when ctx is done
+func (f *Files) CreateTagContext(ctx context.Context, name string, userVisible, userAssignable bool) (SystemTag, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"name":           name,
+		"userVisible":    userVisible,
+		"userAssignable": userAssignable,
+	})
+	if err != nil {
+		return SystemTag{}, err
+	}
+
+	header := http.Header{"Content-Type": {"application/json"}}
+	response, err := f.do(ctx, "creating the tag", http.MethodPost, davRoot+"/systemtags", "/"+name, bytes.NewReader(body), header, tagErrors)
+	if err != nil {
+		return SystemTag{}, err
+	}
+	response.Body.Close()
+
+	// The server returns the url of the new tag, e.g. /remote.php/dav/systemtags/42
+	id, err := strconv.ParseInt(path.Base(response.Header.Get("Content-Location")), 10, 64)
+	if err != nil {
+		return SystemTag{}, errors.New("Tag id not found in response")
+	}
+	// Only admins may create restricted tags and they can assign all tags
+	return SystemTag{ID: id, Name: name, UserVisible: userVisible, UserAssignable: userAssignable, CanAssign: true}, nil
+}
+
+// DeleteTag removes the system tag from the server and all files
+// Returns ErrNotFound when the tag does not exist
+func (f *Files) DeleteTag(id int64) error {
+	return f.DeleteTagContext(context.Background(), id)
+}
+
+// DeleteTagContext is like DeleteTag but aborts the request when ctx is done
+func (f *Files) DeleteTagContext(ctx context.Context, id int64) error {
+	response, err := f.do(ctx, "deleting the tag", http.MethodDelete, davRoot+"/systemtags"+idPath(id), idPath(id), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// ListFileTags returns the system tags assigned to the file with the given id
+// Returns ErrNotFound when the file does not exist
+func (f *Files) ListFileTags(fileID int64) ([]SystemTag, error) {
+	return f.ListFileTagsContext(context.Background(), fileID)
+}
+
+// ListFileTagsContext is like ListFileTags but aborts the request when ctx is done
+func (f *Files) ListFileTagsContext(ctx context.Context, fileID int64) ([]SystemTag, error) {
+	return f.listTags(ctx, "listing the tags of the file", relationsURL(fileID), idPath(fileID))
+}
+
+// AssignTag adds the system tag to the file with the given id
+// Returns ErrNotFound when the file or tag does not exist and
+// ErrInsufficientPermissions when the user may not assign the tag
+func (f *Files) AssignTag(fileID, tagID int64) error {
+	return f.AssignTagContext(context.Background(), fileID, tagID)
+}
+
+// AssignTagContext is like AssignTag but aborts the request when ctx is done
+func (f *Files) AssignTagContext(ctx context.Context, fileID, tagID int64) error {
+	response, err := f.do(ctx, "assigning the tag", http.MethodPut, relationsURL(fileID)+idPath(tagID), idPath(fileID), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// UnassignTag removes the system tag from the file with the given id
+// Returns ErrNotFound when the file does not exist or does not have the tag
+func (f *Files) UnassignTag(fileID, tagID int64) error {
+	return f.UnassignTagContext(context.Background(), fileID, tagID)
+}
+
+// UnassignTagContext is like UnassignTag but aborts the request when ctx is done
+func (f *Files) UnassignTagContext(ctx context.Context, fileID, tagID int64) error {
+	response, err := f.do(ctx, "unassigning the tag", http.MethodDelete, relationsURL(fileID)+idPath(tagID), idPath(fileID), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// FindFilesByTag returns the files and folders that have all of the given system tags
+func (f *Files) FindFilesByTag(tagIDs ...int64) ([]FileInfo, error) {
+	return f.FindFilesByTagContext(context.Background(), tagIDs...)
+}
+
+// FindFilesByTagContext is like FindFilesByTag but aborts the request when ctx is done
+func (f *Files) FindFilesByTagContext(ctx context.Context, tagIDs ...int64) ([]FileInfo, error) {
+	if len(tagIDs) == 0 {
+		return nil, errors.New("At least one tag is required")
+	}
+
+	var rules strings.Builder
+	for _, id := range tagIDs {
+		rules.WriteString("<oc:systemtag>" + strconv.FormatInt(id, 10) + "</oc:systemtag>")
+	}
+	return f.filterFiles(ctx, "finding the files by tag", rules.String())
+}
@@ -0,0 +1,77 @@
+package files
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSystemTags(t *testing.T) {
+	server, api := newTestFiles(t)
+	server.WriteFile("/Invoices/2023.pdf", []byte("2023"))
+	server.WriteFile("/Invoices/2024.pdf", []byte("2024"))
+
+	retain, err := api.CreateTag("retain-10y", true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legal, err := api.CreateTag("legal-hold", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retain.ID == 0 || retain.ID == legal.ID || !retain.CanAssign {
+		t.Errorf("Unexpected tags %+v %+v", retain, legal)
+	}
+	if _, err := api.CreateTag("retain-10y", true, true); !errors.Is(err, ErrTagAlreadyExists) {
+		t.Errorf("Expected ErrTagAlreadyExists, got %v", err)
+	}
+
+	tags, err := api.ListTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tags, []SystemTag{retain, legal}) {
+		t.Errorf("Unexpected tags %+v", tags)
+	}
+
+	id2023, id2024 := server.FileID("/Invoices/2023.pdf"), server.FileID("/Invoices/2024.pdf")
+	for _, assignment := range [][2]int64{{id2023, retain.ID}, {id2024, retain.ID}, {id2023, legal.ID}} {
+		if err := api.AssignTag(assignment[0], assignment[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(server.Tags("/Invoices/2023.pdf"), []string{"legal-hold", "retain-10y"}) {
+		t.Errorf("Unexpected tags %v", server.Tags("/Invoices/2023.pdf"))
+	}
+	if tags, err := api.ListFileTags(id2024); err != nil || !reflect.DeepEqual(tags, []SystemTag{retain}) {
+		t.Errorf("Unexpected tags %+v %v", tags, err)
+	}
+
+	found, err := api.FindFilesByTag(retain.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names(found), []string{"/Invoices/2023.pdf", "/Invoices/2024.pdf"}) {
+		t.Errorf("Unexpected files %v", names(found))
+	}
+	if found, _ := api.FindFilesByTag(retain.ID, legal.ID); !reflect.DeepEqual(names(found), []string{"/Invoices/2023.pdf"}) {
+		t.Errorf("Unexpected files %v", names(found))
+	}
+
+	if err := api.UnassignTag(id2023, legal.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.UnassignTag(id2023, legal.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if err := api.AssignTag(id2023, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	if err := api.DeleteTag(retain.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(server.Tags("/Invoices/2024.pdf")) != 0 {
+		t.Error("Deleted tags should be removed from the files")
+	}
+}
@@ -0,0 +1,131 @@
+package files
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const trashPropfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:oc="http://owncloud.org/ns" xmlns:nc="http://nextcloud.org/ns">
+	<d:prop>` + fileProps + `	<nc:trashbin-filename/>
+		<nc:trashbin-original-location/>
+		<nc:trashbin-deletion-time/>
+	</d:prop>
+</d:propfind>`
+
+// TrashItem is a deleted file or folder in the trash bin.
+// Path and Name of the embedded FileInfo are the name in the trash bin,
+// which is needed to restore or delete the item.
+type TrashItem struct {
+	FileInfo
+	// OriginalName is the name of the file before it was deleted
+	OriginalName string
+	// OriginalLocation is the path the file was deleted from, starting with a slash
+	OriginalLocation string
+	DeletionTime     time.Time
+}
+
+// trashRoot is the unescaped path of the trash bin below the server url
+func trashRoot(user string) string {
+	return davRoot + "/trashbin/" + user + "/trash"
+}
+
+func trashURL(user, name string) string {
+	return escapePath(trashRoot(user) + cleanPath(name))
+}
+
+// ListTrash returns the deleted files and folders of the user
+func (f *Files) ListTrash() ([]TrashItem, error) {
+	return f.ListTrashContext(context.Background())
+}
+
+// ListTrashContext is like ListTrash but aborts the requests when ctx is done
+func (f *Files) ListTrashContext(ctx context.Context) ([]TrashItem, error) {
+	user, err := f.userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	responses, names, err := f.propfind(ctx, "listing the trash bin", trashURL(user, "/"), "/", trashRoot(user), trashPropfindBody, DepthOne)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]TrashItem, 0, len(responses))
+	for i, r := range responses {
+		if names[i] == "/" {
+			continue
+		}
+		prop := r.prop()
+		item := TrashItem{
+			FileInfo:         prop.fileInfo(names[i]),
+			OriginalName:     prop.TrashbinFilename,
+			OriginalLocation: cleanPath(prop.TrashbinOriginalLocation),
+		}
+		if deleted, err := strconv.ParseInt(prop.TrashbinDeletionTime, 10, 64); err == nil {
+			item.DeletionTime = time.Unix(deleted, 0)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// RestoreTrashItem moves the item with the given name in the trash bin back to its original location.
+// When the original folder does not exist anymore, the server restores it to the root folder.
+// Returns ErrNotFound when the item is not in the trash bin
+func (f *Files) RestoreTrashItem(name string) error {
+	return f.RestoreTrashItemContext(context.Background(), name)
+}
+
+// RestoreTrashItemContext is like RestoreTrashItem but aborts the requests when ctx is done
+func (f *Files) RestoreTrashItemContext(ctx context.Context, name string) error {
+	user, err := f.userID(ctx)
+	if err != nil {
+		return err
+	}
+	restore := escapePath(davRoot + "/trashbin/" + user + "/restore" + cleanPath(name))
+	header := http.Header{"Destination": {f.nc.ServerURL + restore}, "Overwrite": {"F"}}
+	response, err := f.do(ctx, "restoring the file", "MOVE", trashURL(user, name), cleanPath(name), nil, header, nil)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// DeleteTrashItem permanently deletes the item with the given name from the trash bin
+// Returns ErrNotFound when the item is not in the trash bin
+func (f *Files) DeleteTrashItem(name string) error {
+	return f.DeleteTrashItemContext(context.Background(), name)
+}
+
+// DeleteTrashItemContext is like DeleteTrashItem but aborts the requests when ctx is done
+func (f *Files) DeleteTrashItemContext(ctx context.Context, name string) error {
+	user, err := f.userID(ctx)
+	if err != nil {
+		return err
+	}
+	response, err := f.do(ctx, "deleting the file from the trash bin", http.MethodDelete, trashURL(user, name), cleanPath(name), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// EmptyTrash permanently deletes all items in the trash bin
+func (f *Files) EmptyTrash() error {
+	return f.EmptyTrashContext(context.Background())
+}
+
+// EmptyTrashContext is like EmptyTrash but aborts the requests when ctx is done
+func (f *Files) EmptyTrashContext(ctx context.Context) error {
+	user, err := f.userID(ctx)
+	if err != nil {
+		return err
+	}
+	response, err := f.do(ctx, "emptying the trash bin", http.MethodDelete, trashURL(user, "/"), "/", nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
@@ -0,0 +1,71 @@
+package files
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrashbin(t *testing.T) {
+	server, api := newTestFiles(t)
+	server.WriteFile("/Documents/contract.pdf", []byte("signed"))
+	server.WriteFile("/Photos/2024/beach.jpg", []byte("sand"))
+	server.WriteFile("/notes.txt", []byte("old notes"))
+
+	for _, name := range []string{"/Documents/contract.pdf", "/Photos", "/notes.txt"} {
+		if err := api.Delete(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server.WriteFile("/notes.txt", []byte("new notes"))
+
+	items, err := api.ListTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 items, got %+v", items)
+	}
+	contract, photos, notes := items[1], items[0], items[2]
+	if contract.OriginalName != "contract.pdf" || contract.OriginalLocation != "/Documents/contract.pdf" || contract.Size != 6 || contract.IsDir {
+		t.Errorf("Unexpected item %+v", contract)
+	}
+	if !strings.HasPrefix(contract.Name, "contract.pdf.d") || time.Since(contract.DeletionTime) > time.Minute {
+		t.Errorf("Unexpected item %+v", contract)
+	}
+	if !photos.IsDir || photos.OriginalLocation != "/Photos" || photos.Size != 4 {
+		t.Errorf("Unexpected item %+v", photos)
+	}
+
+	if err := api.RestoreTrashItem(photos.Name); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.RestoreTrashItem(notes.Name); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := server.ReadFile("/Photos/2024/beach.jpg"); string(content) != "sand" {
+		t.Error("The folder was not restored")
+	}
+	if content, _ := server.ReadFile("/notes (restored).txt"); string(content) != "old notes" {
+		t.Error("The file should be restored next to the new one")
+	}
+
+	if err := api.RestoreTrashItem(photos.Name); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if err := api.DeleteTrashItem(contract.Name); err != nil {
+		t.Fatal(err)
+	}
+	if len(server.Trash()) != 0 {
+		t.Errorf("Unexpected trash %v", server.Trash())
+	}
+
+	api.Delete("/notes.txt")
+	if err := api.EmptyTrash(); err != nil {
+		t.Fatal(err)
+	}
+	if items, err := api.ListTrash(); err != nil || len(items) != 0 {
+		t.Errorf("The trash bin should be empty, got %v %v", items, err)
+	}
+}
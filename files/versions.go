@@ -0,0 +1,152 @@
+package files
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const versionsPropfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:oc="http://owncloud.org/ns" xmlns:nc="http://nextcloud.org/ns">
+	<d:prop>` + fileProps + `	<nc:version-label/>
+		<nc:version-author/>
+	</d:prop>
+</d:propfind>`
+
+// FileVersion is an older version of a file.
+// Name of the embedded FileInfo is the id of the version and FileID the id of the file.
+type FileVersion struct {
+	FileInfo
+	// Label is given by the user, it needs Nextcloud 26
+	Label string
+	// Author is the user who created the version, it needs Nextcloud 27
+	Author string
+}
+
+// versionsRoot is the unescaped path of the versions of the file below the server url
+func versionsRoot(user string, fileID int64) string {
+	return davRoot + "/versions/" + user + "/versions/" + strconv.FormatInt(fileID, 10)
+}
+
+func versionURL(user string, fileID int64, version string) string {
+	return escapePath(versionsRoot(user, fileID) + cleanPath(version))
+}
+
+// ListVersions returns the older versions of the file with the given id
+// Returns ErrNotFound when the file does not exist
+func (f *Files) ListVersions(fileID int64) ([]FileVersion, error) {
+	return f.ListVersionsContext(context.Background(), fileID)
+}
+
+// ListVersionsContext is like ListVersions but aborts the requests when ctx is done
+func (f *Files) ListVersionsContext(ctx context.Context, fileID int64) ([]FileVersion, error) {
+	user, err := f.userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id := "/" + strconv.FormatInt(fileID, 10)
+	responses, names, err := f.propfind(ctx, "listing the versions", versionURL(user, fileID, "/"), id, versionsRoot(user, fileID), versionsPropfindBody, DepthOne)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]FileVersion, 0, len(responses))
+	for i, r := range responses {
+		if names[i] == "/" {
+			continue
+		}
+		prop := r.prop()
+		version := FileVersion{FileInfo: prop.fileInfo(names[i]), Label: prop.VersionLabel, Author: prop.VersionAuthor}
+		version.FileID = fileID
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+// ListVersionsByPath returns the older versions of the file at the given path
+// Returns ErrNotFound when the file does not exist
+func (f *Files) ListVersionsByPath(name string) ([]FileVersion, error) {
+	return f.ListVersionsByPathContext(context.Background(), name)
+}
+
+// ListVersionsByPathContext is like ListVersionsByPath but aborts the requests when ctx is done
+func (f *Files) ListVersionsByPathContext(ctx context.Context, name string) ([]FileVersion, error) {
+	info, err := f.StatContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return f.ListVersionsContext(ctx, info.FileID)
+}
+
+// DownloadVersion returns the contents of a version of the file, the caller has to close the reader
+// Returns ErrNotFound when the file or the version does not exist
+func (f *Files) DownloadVersion(fileID int64, version string) (io.ReadCloser, error) {
+	return f.DownloadVersionContext(context.Background(), fileID, version)
+}
+
+// DownloadVersionContext is like DownloadVersion but aborts the requests when ctx is done
+func (f *Files) DownloadVersionContext(ctx context.Context, fileID int64, version string) (io.ReadCloser, error) {
+	user, err := f.userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	u := versionURL(user, fileID, version)
+	response, err := f.do(ctx, "downloading the version", http.MethodGet, u, "/"+version, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+// DownloadVersionByPath returns the contents of a version of the file at the given path, the caller has to close the reader
+// Returns ErrNotFound when the file or the version does not exist
+func (f *Files) DownloadVersionByPath(name, version string) (io.ReadCloser, error) {
+	return f.DownloadVersionByPathContext(context.Background(), name, version)
+}
+
+// DownloadVersionByPathContext is like DownloadVersionByPath but aborts the requests when ctx is done
+func (f *Files) DownloadVersionByPathContext(ctx context.Context, name, version string) (io.ReadCloser, error) {
+	info, err := f.StatContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return f.DownloadVersionContext(ctx, info.FileID, version)
+}
+
+// RestoreVersion replaces the file with the given version, the current content is kept as new version
+// Returns ErrNotFound when the file or the version does not exist
+func (f *Files) RestoreVersion(fileID int64, version string) error {
+	return f.RestoreVersionContext(context.Background(), fileID, version)
+}
+
+// RestoreVersionContext is like RestoreVersion but aborts the requests when ctx is done
+func (f *Files) RestoreVersionContext(ctx context.Context, fileID int64, version string) error {
+	user, err := f.userID(ctx)
+	if err != nil {
+		return err
+	}
+	restore := escapePath(davRoot + "/versions/" + user + "/restore/target")
+	header := http.Header{"Destination": {f.nc.ServerURL + restore}}
+	u := versionURL(user, fileID, version)
+	response, err := f.do(ctx, "restoring the version", "MOVE", u, "/"+version, nil, header, nil)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// RestoreVersionByPath replaces the file at the given path with the given version
+// Returns ErrNotFound when the file or the version does not exist
+func (f *Files) RestoreVersionByPath(name, version string) error {
+	return f.RestoreVersionByPathContext(context.Background(), name, version)
+}
+
+// RestoreVersionByPathContext is like RestoreVersionByPath but aborts the requests when ctx is done
+func (f *Files) RestoreVersionByPathContext(ctx context.Context, name, version string) error {
+	info, err := f.StatContext(ctx, name)
+	if err != nil {
+		return err
+	}
+	return f.RestoreVersionContext(ctx, info.FileID, version)
+}
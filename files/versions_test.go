@@ -0,0 +1,93 @@
+package files
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestVersions(t *testing.T) {
+	server, api := newTestFiles(t)
+	server.WriteFile("/Contracts/lease.txt", []byte("draft"))
+	if err := api.Upload("/Contracts/lease.txt", strings.NewReader("final")); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := api.ListVersionsByPath("/Contracts/lease.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileID := server.FileID("/Contracts/lease.txt")
+	if len(versions) != 1 || versions[0].FileID != fileID || versions[0].Size != 5 || versions[0].Author != "alice" || versions[0].ModTime.IsZero() {
+		t.Fatalf("Unexpected versions %+v", versions)
+	}
+
+	byID, err := api.ListVersions(fileID)
+	if err != nil || len(byID) != 1 || byID[0].Name != versions[0].Name {
+		t.Errorf("Listing by id should return the same versions, got %+v %v", byID, err)
+	}
+
+	reader, err := api.DownloadVersion(fileID, versions[0].Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(content) != "draft" {
+		t.Errorf("Unexpected content %q", content)
+	}
+
+	if err := api.RestoreVersion(fileID, versions[0].Name); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := server.ReadFile("/Contracts/lease.txt"); string(content) != "draft" {
+		t.Errorf("The version was not restored, got %q", content)
+	}
+	if len(server.Versions("/Contracts/lease.txt")) != 2 {
+		t.Error("The replaced content should be kept as version")
+	}
+
+	if _, err := api.DownloadVersion(fileID, "1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if _, err := api.ListVersionsByPath("/Contracts/missing.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestVersionsByPath(t *testing.T) {
+	server, api := newTestFiles(t)
+	server.WriteFile("/Contracts/lease.txt", []byte("draft"))
+	if err := api.Upload("/Contracts/lease.txt", strings.NewReader("final")); err != nil {
+		t.Fatal(err)
+	}
+	versions, err := api.ListVersionsByPath("/Contracts/lease.txt")
+	if err != nil || len(versions) != 1 {
+		t.Fatalf("Unexpected versions %+v %v", versions, err)
+	}
+
+	reader, err := api.DownloadVersionByPath("/Contracts/lease.txt", versions[0].Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(content) != "draft" {
+		t.Errorf("Unexpected content %q", content)
+	}
+
+	if err := api.RestoreVersionByPath("/Contracts/lease.txt", versions[0].Name); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := server.ReadFile("/Contracts/lease.txt"); string(content) != "draft" {
+		t.Errorf("The version was not restored, got %q", content)
+	}
+
+	if _, err := api.DownloadVersionByPath("/Contracts/missing.txt", versions[0].Name); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if err := api.RestoreVersionByPath("/Contracts/missing.txt", versions[0].Name); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
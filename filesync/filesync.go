@@ -0,0 +1,679 @@
+// Package filesync keeps a local folder and a folder of the user's files on the server in sync.
+// Changes are detected with the ETags of the server and a local journal of the last sync.
+package filesync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nextcloud/nextcloudgo/files"
+)
+
+// partSuffix is appended to files while they are downloaded
+const partSuffix = ".nextcloudgo-part"
+
+// ErrTypeMismatch is reported when a file on one side is a folder on the other side
+var ErrTypeMismatch = errors.New("A file and a folder have the same path")
+
+// Direction in which changes are transferred
+type Direction int
+
+const (
+	// TwoWay transfers changes in both directions
+	TwoWay Direction = iota
+	// Upload makes the remote folder match the local one. Remote changes
+	// are overwritten, files that were never synced are left alone.
+	Upload
+	// Download makes the local folder match the remote one. Local changes
+	// are overwritten, files that were never synced are left alone.
+	Download
+)
+
+// ConflictPolicy decides what happens to files that changed on both sides
+type ConflictPolicy int
+
+const (
+	// KeepBoth renames the local file to a conflicted copy, which is uploaded
+	// as well, and downloads the remote file
+	KeepBoth ConflictPolicy = iota
+	// PreferLocal overwrites the remote file
+	PreferLocal
+	// PreferRemote overwrites the local file
+	PreferRemote
+)
+
+// Options configure a sync
+type Options struct {
+	Direction Direction
+	Conflicts ConflictPolicy
+	// Journal is the path of the journal, DefaultJournal in the local folder when empty
+	Journal string
+	// Ignore lists patterns as understood by path.Match, which are matched against
+	// the name and the slash separated path relative to the synced folders
+	Ignore []string
+	// DryRun only computes the actions, no changes are made
+	DryRun bool
+}
+
+// ActionType is the kind of change an Action performs
+type ActionType string
+
+// Actions performed by the sync
+const (
+	ActionMkdirLocal   ActionType = "mkdir-local"
+	ActionMkdirRemote  ActionType = "mkdir-remote"
+	ActionUpload       ActionType = "upload"
+	ActionDownload     ActionType = "download"
+	ActionConflict     ActionType = "conflict"
+	ActionDeleteLocal  ActionType = "delete-local"
+	ActionDeleteRemote ActionType = "delete-remote"
+)
+
+// Action is a single change the sync performs
+type Action struct {
+	Type ActionType
+	// Path is relative to the synced folders and slash separated
+	Path  string
+	IsDir bool
+}
+
+func (a Action) String() string {
+	return fmt.Sprintf("%s %s", a.Type, a.Path)
+}
+
+// phase returns the order in which actions are applied: folders are created
+// before their contents, files are deleted before their folders
+func (a Action) phase() int {
+	switch a.Type {
+	case ActionMkdirLocal, ActionMkdirRemote:
+		return 0
+	case ActionDeleteLocal, ActionDeleteRemote:
+		if a.IsDir {
+			return 3
+		}
+		return 2
+	}
+	return 1
+}
+
+// Result is the outcome of a single action
+type Result struct {
+	Action Action
+	// Err is nil when the action succeeded or was not applied in a dry run
+	Err error
+}
+
+// Report lists the outcome of every action of the sync
+type Report struct {
+	DryRun  bool
+	Results []Result
+}
+
+// Failed returns the results of all actions that failed
+func (r Report) Failed() []Result {
+	var failed []Result
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// entry is the current state of a file on one side
+type entry struct {
+	IsDir   bool
+	Size    int64
+	ModTime time.Time
+	// ETag is only known for remote files
+	ETag string
+}
+
+type syncer struct {
+	files   files.Files
+	local   string
+	remote  string
+	options Options
+	journal journal
+	// journalName is the slash separated path of the journal in the local folder
+	journalName string
+	rootETag    string
+
+	localTree  map[string]entry
+	remoteTree map[string]entry
+	// next is the journal after the sync
+	next map[string]journalEntry
+}
+
+// Sync syncs the local folder with the remote folder of the user's files.
+// The returned error is only set when the folders could not be compared,
+// failed transfers are listed in the report.
+func Sync(f files.Files, localDir, remoteDir string, options Options) (Report, error) {
+	return SyncContext(context.Background(), f, localDir, remoteDir, options)
+}
+
+// SyncContext is like Sync but aborts the requests when ctx is done
+func SyncContext(ctx context.Context, f files.Files, localDir, remoteDir string, options Options) (Report, error) {
+	s := &syncer{files: f, local: filepath.Clean(localDir), remote: path.Clean("/" + remoteDir), options: options}
+	if s.options.Journal == "" {
+		s.options.Journal = filepath.Join(s.local, DefaultJournal)
+	}
+	if rel, err := filepath.Rel(s.local, s.options.Journal); err == nil {
+		s.journalName = filepath.ToSlash(rel)
+	}
+
+	var err error
+	if s.journal, err = loadJournal(s.options.Journal, s.remote); err != nil {
+		return Report{}, err
+	}
+	if s.localTree, err = s.walkLocal(); err != nil {
+		return Report{}, err
+	}
+	if s.remoteTree, err = s.walkRemote(ctx); err != nil {
+		return Report{}, err
+	}
+
+	results, actions := s.plan()
+	report := Report{DryRun: options.DryRun}
+	if options.DryRun {
+		for _, action := range actions {
+			report.Results = append(report.Results, Result{Action: action})
+		}
+		report.Results = append(report.Results, results...)
+		return report, nil
+	}
+
+	if err := s.prepareRoots(ctx); err != nil {
+		return Report{}, err
+	}
+
+	s.next = map[string]journalEntry{}
+	for _, action := range actions {
+		err := s.apply(ctx, action)
+		report.Results = append(report.Results, Result{Action: action, Err: err})
+	}
+	report.Results = append(report.Results, results...)
+	s.updateJournal(report.Results)
+
+	return report, journal{Remote: s.remote, Ignore: s.options.Ignore, Entries: s.next}.save(s.options.Journal)
+}
+
+func (s *syncer) localPath(name string) string {
+	return filepath.Join(s.local, filepath.FromSlash(name))
+}
+
+func (s *syncer) remotePath(name string) string {
+	return path.Join(s.remote, name)
+}
+
+// ignored returns true for the journal, partial downloads and paths matching an ignore pattern
+func (s *syncer) ignored(name string) bool {
+	if name == s.journalName || strings.HasSuffix(name, partSuffix) {
+		return true
+	}
+	for _, pattern := range s.options.Ignore {
+		if ok, _ := path.Match(pattern, path.Base(name)); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *syncer) walkLocal() (map[string]entry, error) {
+	tree := map[string]entry{}
+	if _, err := os.Stat(s.local); errors.Is(err, os.ErrNotExist) && s.options.Direction != Upload {
+		return tree, nil
+	}
+
+	err := filepath.WalkDir(s.local, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == s.local {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.local, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if s.ignored(name) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Symlinks and other special files are not synced
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		e := entry{IsDir: d.IsDir(), ModTime: info.ModTime()}
+		if !e.IsDir {
+			e.Size = info.Size()
+		}
+		tree[name] = e
+		return nil
+	})
+	return tree, err
+}
+
+// walkRemote lists the remote folder. Folders with the same ETag as in the
+// journal did not change, so their contents are taken from the journal.
+// The journal only keeps the ETag of folders whose remote contents were all journaled,
+// and the ETags are not used when the ignore patterns changed.
+func (s *syncer) walkRemote(ctx context.Context) (map[string]entry, error) {
+	tree := map[string]entry{}
+	root, err := s.files.StatContext(ctx, s.remote)
+	if errors.Is(err, files.ErrNotFound) && s.options.Direction != Download {
+		return tree, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sameIgnore := slices.Equal(s.journal.Ignore, s.options.Ignore)
+	var walk func(dir, etag string) error
+	walk = func(dir, etag string) error {
+		if j, ok := s.journal.Entries[dir]; ok && sameIgnore && j.IsDir && j.ETag != "" && j.ETag == etag {
+			for name, e := range s.journal.Entries {
+				if name != "" && (dir == "" || strings.HasPrefix(name, dir+"/")) && !s.ignored(name) {
+					tree[name] = entry{IsDir: e.IsDir, ETag: e.ETag}
+				}
+			}
+			return nil
+		}
+
+		children, err := s.files.ListContext(ctx, s.remotePath(dir))
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			name := path.Join(dir, child.Name)
+			if s.ignored(name) {
+				continue
+			}
+			tree[name] = entry{IsDir: child.IsDir, Size: child.Size, ModTime: child.ModTime, ETag: child.ETag}
+			if child.IsDir {
+				if err := walk(name, child.ETag); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	s.rootETag = root.ETag
+	return tree, walk("", root.ETag)
+}
+
+// prepareRoots creates the synced folders when they do not exist yet
+func (s *syncer) prepareRoots(ctx context.Context) error {
+	if err := os.MkdirAll(s.local, 0755); err != nil {
+		return err
+	}
+	return s.files.MkdirAllContext(ctx, s.remote)
+}
+
+// plan returns the actions of the sync and the results of paths that can not be synced
+func (s *syncer) plan() ([]Result, []Action) {
+	names := map[string]bool{}
+	for name := range s.localTree {
+		names[name] = true
+	}
+	for name := range s.remoteTree {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var results []Result
+	planned := map[string]ActionType{}
+	var dirs []string
+	for _, name := range sorted {
+		l, inLocal := s.localTree[name]
+		r, inRemote := s.remoteTree[name]
+		if inLocal && inRemote && l.IsDir != r.IsDir {
+			results = append(results, Result{Action: Action{Type: ActionConflict, Path: name}, Err: ErrTypeMismatch})
+			continue
+		}
+		if (inLocal && l.IsDir) || (inRemote && r.IsDir) {
+			dirs = append(dirs, name)
+			continue
+		}
+		if t := s.planFile(name); t != "" {
+			planned[name] = t
+		}
+	}
+
+	// Deepest folders first, so a folder is only deleted when all its contents are
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if t := s.planDir(dirs[i], planned); t != "" {
+			planned[dirs[i]] = t
+		}
+	}
+
+	actions := make([]Action, 0, len(planned))
+	for name, t := range planned {
+		isDir := s.localTree[name].IsDir || s.remoteTree[name].IsDir
+		actions = append(actions, Action{Type: t, Path: name, IsDir: isDir})
+	}
+	sort.Slice(actions, func(i, j int) bool {
+		if actions[i].phase() != actions[j].phase() {
+			return actions[i].phase() < actions[j].phase()
+		}
+		if actions[i].phase() == 3 {
+			return actions[i].Path > actions[j].Path
+		}
+		return actions[i].Path < actions[j].Path
+	})
+	return results, actions
+}
+
+func (s *syncer) planFile(name string) ActionType {
+	l, inLocal := s.localTree[name]
+	r, inRemote := s.remoteTree[name]
+	j, inJournal := s.journal.Entries[name]
+
+	localChanged := inLocal && (!inJournal || j.IsDir || l.Size != j.Size || l.ModTime.UnixNano() != j.ModTime)
+	remoteChanged := inRemote && (!inJournal || j.IsDir || r.ETag != j.ETag)
+	// Without journal, files of the same size and modification time are considered in sync
+	if inLocal && inRemote && !inJournal && l.Size == r.Size && l.ModTime.Truncate(time.Second).Equal(r.ModTime) {
+		localChanged, remoteChanged = false, false
+	}
+
+	switch s.options.Direction {
+	case Upload:
+		if inLocal && (localChanged || remoteChanged || !inRemote) {
+			return ActionUpload
+		}
+		if !inLocal && inRemote && inJournal {
+			return ActionDeleteRemote
+		}
+	case Download:
+		if inRemote && (localChanged || remoteChanged || !inLocal) {
+			return ActionDownload
+		}
+		if inLocal && !inRemote && inJournal {
+			return ActionDeleteLocal
+		}
+	default:
+		switch {
+		case inLocal && inRemote && localChanged && remoteChanged:
+			switch s.options.Conflicts {
+			case PreferLocal:
+				return ActionUpload
+			case PreferRemote:
+				return ActionDownload
+			}
+			return ActionConflict
+		case inLocal && inRemote && localChanged:
+			return ActionUpload
+		case inLocal && inRemote && remoteChanged:
+			return ActionDownload
+		case inLocal && !inRemote && inJournal && !localChanged:
+			return ActionDeleteLocal
+		case inLocal && !inRemote:
+			return ActionUpload
+		case inRemote && !inLocal && inJournal && !remoteChanged:
+			return ActionDeleteRemote
+		case inRemote && !inLocal:
+			return ActionDownload
+		}
+	}
+	return ""
+}
+
+func (s *syncer) planDir(name string, planned map[string]ActionType) ActionType {
+	_, inLocal := s.localTree[name]
+	_, inRemote := s.remoteTree[name]
+	_, inJournal := s.journal.Entries[name]
+	if inLocal == inRemote {
+		return ""
+	}
+
+	// deleted returns true when every descendant in the tree will be deleted
+	deleted := func(tree map[string]entry, action ActionType) bool {
+		for child := range tree {
+			if strings.HasPrefix(child, name+"/") && planned[child] != action {
+				return false
+			}
+		}
+		return true
+	}
+
+	if inLocal {
+		if inJournal && s.options.Direction != Upload && deleted(s.localTree, ActionDeleteLocal) {
+			return ActionDeleteLocal
+		}
+		if s.options.Direction != Download {
+			return ActionMkdirRemote
+		}
+		return ""
+	}
+
+	if inJournal && s.options.Direction != Download && deleted(s.remoteTree, ActionDeleteRemote) {
+		return ActionDeleteRemote
+	}
+	if s.options.Direction != Upload {
+		return ActionMkdirLocal
+	}
+	return ""
+}
+
+func (s *syncer) apply(ctx context.Context, action Action) error {
+	name := action.Path
+	switch action.Type {
+	case ActionMkdirLocal:
+		if err := os.Mkdir(s.localPath(name), 0755); err != nil && !errors.Is(err, os.ErrExist) {
+			return err
+		}
+		s.next[name] = journalEntry{IsDir: true}
+	case ActionMkdirRemote:
+		if err := s.files.MkdirContext(ctx, s.remotePath(name)); err != nil && !errors.Is(err, files.ErrAlreadyExists) {
+			return err
+		}
+		s.next[name] = journalEntry{IsDir: true}
+	case ActionUpload:
+		return s.upload(ctx, name)
+	case ActionDownload:
+		return s.download(ctx, name)
+	case ActionConflict:
+		conflicted := conflictName(name, time.Now())
+		if err := os.Rename(s.localPath(name), s.localPath(conflicted)); err != nil {
+			return err
+		}
+		if err := s.download(ctx, name); err != nil {
+			return err
+		}
+		return s.upload(ctx, conflicted)
+	case ActionDeleteLocal:
+		// The contents were deleted by earlier actions, folders that still hold
+		// ignored or special files are kept
+		err := os.Remove(s.localPath(name))
+		if action.IsDir && err != nil && !errors.Is(err, os.ErrNotExist) {
+			if entries, readErr := os.ReadDir(s.localPath(name)); readErr == nil && len(entries) > 0 {
+				s.next[name] = journalEntry{IsDir: true}
+				return nil
+			}
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	case ActionDeleteRemote:
+		if action.IsDir {
+			// A recursive delete would remove ignored files, so only empty folders are deleted
+			children, err := s.files.ListContext(ctx, s.remotePath(name))
+			if errors.Is(err, files.ErrNotFound) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if len(children) > 0 {
+				s.next[name] = journalEntry{IsDir: true}
+				return nil
+			}
+		}
+		err := s.files.DeleteContext(ctx, s.remotePath(name))
+		if errors.Is(err, files.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *syncer) upload(ctx context.Context, name string) error {
+	file, err := os.Open(s.localPath(name))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	// The modification time is kept, so files can be compared without journal
+	if err := s.files.UploadWithModTimeContext(ctx, s.remotePath(name), file, stat.ModTime()); err != nil {
+		return err
+	}
+	info, err := s.files.StatContext(ctx, s.remotePath(name))
+	if err != nil {
+		return err
+	}
+
+	s.next[name] = journalEntry{ETag: info.ETag, Size: stat.Size(), ModTime: stat.ModTime().UnixNano()}
+	return nil
+}
+
+func (s *syncer) download(ctx context.Context, name string) error {
+	reader, err := s.files.DownloadContext(ctx, s.remotePath(name))
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	target := s.localPath(name)
+	tmp := filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+partSuffix)
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	remote := s.remoteTree[name]
+	if !remote.ModTime.IsZero() {
+		os.Chtimes(tmp, remote.ModTime, remote.ModTime)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	stat, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+	s.next[name] = journalEntry{ETag: remote.ETag, Size: stat.Size(), ModTime: stat.ModTime().UnixNano()}
+	return nil
+}
+
+// conflictName returns the name of the conflicted copy like the desktop client does,
+// e.g. "report (conflicted copy 2024-03-01 120000).pdf"
+func conflictName(name string, now time.Time) string {
+	ext := path.Ext(name)
+	if ext == path.Base(name) {
+		ext = ""
+	}
+	return strings.TrimSuffix(name, ext) + " (conflicted copy " + now.Format("2006-01-02 150405") + ")" + ext
+}
+
+// updateJournal records the state of all files that are in sync.
+// Failed actions keep their old entry, so they are retried on the next sync.
+// Folders only keep their ETag when nothing below them changed, because
+// the ETag of the server changes with every transfer into them.
+func (s *syncer) updateJournal(results []Result) {
+	changed := func(dir string) bool {
+		for _, result := range results {
+			if dir == "" || result.Action.Path == dir || strings.HasPrefix(result.Action.Path, dir+"/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, result := range results {
+		if result.Err == nil {
+			continue
+		}
+		if j, ok := s.journal.Entries[result.Action.Path]; ok {
+			s.next[result.Action.Path] = j
+		} else {
+			delete(s.next, result.Action.Path)
+		}
+	}
+
+	// complete returns true when every remote file below dir is journaled, e.g. not after
+	// an upload-only sync left remote files alone
+	complete := func(dir string) bool {
+		for name := range s.remoteTree {
+			if _, ok := s.next[name]; !ok && (dir == "" || strings.HasPrefix(name, dir+"/")) {
+				return false
+			}
+		}
+		return true
+	}
+
+	var dirs []string
+	for name, l := range s.localTree {
+		r, ok := s.remoteTree[name]
+		if _, done := s.next[name]; done || !ok || l.IsDir != r.IsDir || (changed(name) && !l.IsDir) {
+			continue
+		}
+		if l.IsDir {
+			s.next[name] = journalEntry{IsDir: true}
+			dirs = append(dirs, name)
+			continue
+		}
+		s.next[name] = journalEntry{ETag: r.ETag, Size: l.Size, ModTime: l.ModTime.UnixNano()}
+	}
+
+	for _, name := range dirs {
+		if !changed(name) && complete(name) {
+			s.next[name] = journalEntry{IsDir: true, ETag: s.remoteTree[name].ETag}
+		}
+	}
+	if !changed("") && complete("") {
+		s.next[""] = journalEntry{IsDir: true, ETag: s.rootETag}
+	}
+}
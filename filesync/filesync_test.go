@@ -0,0 +1,336 @@
+package filesync
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nextcloud/nextcloudgo"
+	"github.com/nextcloud/nextcloudgo/files"
+	"github.com/nextcloud/nextcloudgo/files/davtest"
+)
+
+// propfindCounter counts the PROPFIND requests
+type propfindCounter struct {
+	mu        sync.Mutex
+	propfinds int
+}
+
+func (c *propfindCounter) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Method == "PROPFIND" {
+		c.mu.Lock()
+		c.propfinds++
+		c.mu.Unlock()
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func newTestSync(t *testing.T) (*davtest.Server, files.Files, *propfindCounter, string) {
+	t.Helper()
+	server := davtest.NewServer("alice", "secret")
+	t.Cleanup(server.Close)
+
+	counter := &propfindCounter{}
+	nc, err := nextcloudgo.New(server.URL, "alice", "secret", nextcloudgo.WithTransport(counter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, files.New(nc), counter, t.TempDir()
+}
+
+func writeLocal(t *testing.T, dir, name, content string, modTime time.Time) {
+	t.Helper()
+	p := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(p, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readLocal(dir, name string) string {
+	content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		return "<missing>"
+	}
+	return string(content)
+}
+
+func actions(report Report) []string {
+	var actions []string
+	for _, result := range report.Results {
+		actions = append(actions, result.Action.String())
+	}
+	return actions
+}
+
+func TestSyncTwoWay(t *testing.T) {
+	server, api, counter, local := newTestSync(t)
+	yesterday := time.Now().Add(-24 * time.Hour)
+	writeLocal(t, local, "notes.txt", "local notes", yesterday)
+	writeLocal(t, local, "docs/b.txt", "b", yesterday)
+	writeLocal(t, local, "docs/build.tmp", "ignored", yesterday)
+	server.WriteFile("/Sync/docs/c.txt", []byte("c"))
+	server.WriteFile("/Sync/photos/d.jpg", []byte("d"))
+
+	options := Options{Ignore: []string{"*.tmp"}}
+	report, err := Sync(api, local, "/Sync", Options{Ignore: options.Ignore, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"mkdir-local photos",
+		"upload docs/b.txt",
+		"download docs/c.txt",
+		"upload notes.txt",
+		"download photos/d.jpg",
+	}
+	if !reflect.DeepEqual(actions(report), expected) {
+		t.Errorf("Unexpected plan %v", actions(report))
+	}
+	if server.Exists("/Sync/notes.txt") || readLocal(local, "docs/c.txt") != "<missing>" {
+		t.Fatal("Dry run should not change anything")
+	}
+
+	report, err = Sync(api, local, "/Sync", options)
+	if err != nil || len(report.Failed()) != 0 {
+		t.Fatal(err, report.Failed())
+	}
+	if content, _ := server.ReadFile("/Sync/docs/b.txt"); string(content) != "b" || readLocal(local, "photos/d.jpg") != "d" {
+		t.Error("Files were not transferred")
+	}
+	if server.Exists("/Sync/docs/build.tmp") {
+		t.Error("Ignored files should not be uploaded")
+	}
+
+	report, err = Sync(api, local, "/Sync", options)
+	if err != nil || len(report.Results) != 0 {
+		t.Fatalf("Nothing should change, got %v %v", actions(report), err)
+	}
+	// Folders with transfers are listed again once, because their ETag changed by the sync
+	counter.propfinds = 0
+	if _, err := Sync(api, local, "/Sync", options); err != nil {
+		t.Fatal(err)
+	}
+	if counter.propfinds != 1 {
+		t.Errorf("Unchanged folders should not be listed, got %d requests", counter.propfinds)
+	}
+
+	server.WriteFile("/Sync/docs/c.txt", []byte("remote change"))
+	os.Remove(filepath.Join(local, "notes.txt"))
+	os.RemoveAll(filepath.Join(local, "photos"))
+	report, err = Sync(api, local, "/Sync", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{"download docs/c.txt", "delete-remote notes.txt", "delete-remote photos/d.jpg", "delete-remote photos"}
+	if !reflect.DeepEqual(actions(report), expected) {
+		t.Errorf("Unexpected actions %v", actions(report))
+	}
+	if readLocal(local, "docs/c.txt") != "remote change" || server.Exists("/Sync/photos") || server.Exists("/Sync/notes.txt") {
+		t.Error("Changes were not synced")
+	}
+}
+
+func TestSyncConflicts(t *testing.T) {
+	server, api, _, local := newTestSync(t)
+	writeLocal(t, local, "report.md", "draft", time.Now().Add(-time.Hour))
+	if _, err := Sync(api, local, "/", Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	writeLocal(t, local, "report.md", "local edit", time.Now())
+	server.WriteFile("/report.md", []byte("remote edit"))
+	report, err := Sync(api, local, "/", Options{})
+	if err != nil || len(report.Failed()) != 0 {
+		t.Fatal(err, report.Failed())
+	}
+
+	if readLocal(local, "report.md") != "remote edit" {
+		t.Error("The remote file should be downloaded")
+	}
+	matches, _ := filepath.Glob(filepath.Join(local, "report (conflicted copy *).md"))
+	if len(matches) != 1 || readLocal(local, filepath.Base(matches[0])) != "local edit" {
+		t.Fatalf("The local file should be kept as conflicted copy, got %v", matches)
+	}
+	if content, ok := server.ReadFile(filepath.Base(matches[0])); !ok || string(content) != "local edit" {
+		t.Error("The conflicted copy should be uploaded")
+	}
+
+	writeLocal(t, local, "report.md", "local wins", time.Now().Add(time.Hour))
+	server.WriteFile("/report.md", []byte("remote loses"))
+	if _, err := Sync(api, local, "/", Options{Conflicts: PreferLocal}); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := server.ReadFile("/report.md"); string(content) != "local wins" {
+		t.Errorf("The local file should win, got %q", content)
+	}
+}
+
+func TestSyncOneWay(t *testing.T) {
+	server, api, _, local := newTestSync(t)
+	writeLocal(t, local, "site/index.html", "<h1>Hello</h1>", time.Now())
+	server.WriteFile("/Web/unrelated.txt", []byte("keep me"))
+
+	options := Options{Direction: Upload, Journal: filepath.Join(t.TempDir(), "journal.json")}
+	if _, err := Sync(api, local, "/Web", options); err != nil {
+		t.Fatal(err)
+	}
+
+	server.WriteFile("/Web/site/index.html", []byte("defaced"))
+	server.WriteFile("/Web/site/new.html", []byte("remote only"))
+	writeLocal(t, local, "site/about.html", "About", time.Now())
+	report, err := Sync(api, local, "/Web", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(actions(report), []string{"upload site/about.html", "upload site/index.html"}) {
+		t.Errorf("Unexpected actions %v", actions(report))
+	}
+	if content, _ := server.ReadFile("/Web/site/index.html"); string(content) != "<h1>Hello</h1>" {
+		t.Error("Remote changes should be overwritten")
+	}
+	if !server.Exists("/Web/unrelated.txt") || !server.Exists("/Web/site/new.html") || readLocal(local, "site/new.html") != "<missing>" {
+		t.Error("Files that were never synced should be left alone")
+	}
+	if _, err := os.Stat(filepath.Join(local, DefaultJournal)); err == nil {
+		t.Error("The journal should be stored at the given path")
+	}
+}
+
+func TestSyncDeleteKeepsIgnored(t *testing.T) {
+	server, api, _, local := newTestSync(t)
+	yesterday := time.Now().Add(-24 * time.Hour)
+	writeLocal(t, local, "build/app.js", "app", yesterday)
+	writeLocal(t, local, "build/cache.tmp", "local cache", yesterday)
+	writeLocal(t, local, "docs/readme.txt", "readme", yesterday)
+
+	options := Options{Ignore: []string{"*.tmp"}}
+	if _, err := Sync(api, local, "/Sync", options); err != nil {
+		t.Fatal(err)
+	}
+	server.WriteFile("/Sync/docs/index.tmp", []byte("remote cache"))
+
+	if err := api.Delete("/Sync/build"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(local, "docs")); err != nil {
+		t.Fatal(err)
+	}
+	report, err := Sync(api, local, "/Sync", options)
+	if err != nil || len(report.Failed()) != 0 {
+		t.Fatal(err, report.Failed())
+	}
+	expected := []string{"delete-local build/app.js", "delete-remote docs/readme.txt", "delete-remote docs", "delete-local build"}
+	if !reflect.DeepEqual(actions(report), expected) {
+		t.Errorf("Unexpected actions %v", actions(report))
+	}
+
+	if readLocal(local, "build/app.js") != "<missing>" || server.Exists("/Sync/docs/readme.txt") {
+		t.Error("Deleted files should be deleted on the other side")
+	}
+	if readLocal(local, "build/cache.tmp") != "local cache" {
+		t.Error("Ignored local files should be kept")
+	}
+	if content, ok := server.ReadFile("/Sync/docs/index.tmp"); !ok || string(content) != "remote cache" {
+		t.Error("Ignored remote files should be kept")
+	}
+
+	if report, err := Sync(api, local, "/Sync", options); err != nil || len(report.Failed()) != 0 {
+		t.Fatal(err, report.Failed())
+	}
+	if readLocal(local, "build/cache.tmp") != "local cache" || !server.Exists("/Sync/docs/index.tmp") {
+		t.Error("Ignored files should be kept by later syncs")
+	}
+	if server.Exists("/Sync/build") {
+		t.Error("Folders with ignored files should not be created again")
+	}
+}
+
+func TestSyncWithoutJournal(t *testing.T) {
+	server, api, _, local := newTestSync(t)
+	yesterday := time.Now().Add(-24 * time.Hour)
+	writeLocal(t, local, "notes.txt", "notes", yesterday)
+	writeLocal(t, local, "docs/report.md", "report", yesterday)
+	server.WriteFile("/Sync/photos/d.jpg", []byte("d"))
+
+	if _, err := Sync(api, local, "/Sync", Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := api.Stat("/Sync/notes.txt"); err != nil || !info.ModTime.Equal(yesterday.Truncate(time.Second)) {
+		t.Errorf("The modification time should be uploaded, got %v %v", info.ModTime, err)
+	}
+
+	// A lost journal is rebuilt from files with the same size and modification time
+	if err := os.Remove(filepath.Join(local, DefaultJournal)); err != nil {
+		t.Fatal(err)
+	}
+	report, err := Sync(api, local, "/Sync", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 0 {
+		t.Errorf("Nothing should change, got %v", actions(report))
+	}
+	if matches, _ := filepath.Glob(filepath.Join(local, "*conflicted copy*")); len(matches) != 0 {
+		t.Errorf("No conflicted copies should be created, got %v", matches)
+	}
+}
+
+func TestSyncUnjournaledRemoteFiles(t *testing.T) {
+	server, api, _, local := newTestSync(t)
+	writeLocal(t, local, "site/index.html", "<h1>Hello</h1>", time.Now())
+	server.WriteFile("/Web/site/new.html", []byte("remote only"))
+	server.WriteFile("/Web/cache/build.tmp", []byte("ignored"))
+
+	// Unchanged folders of upload-only syncs still contain files that were never journaled
+	upload := Options{Direction: Upload, Ignore: []string{"*.tmp"}}
+	for i := 0; i < 2; i++ {
+		if _, err := Sync(api, local, "/Web", upload); err != nil {
+			t.Fatal(err)
+		}
+	}
+	twoWay := Options{Ignore: []string{"*.tmp"}}
+	if _, err := Sync(api, local, "/Web", twoWay); err != nil {
+		t.Fatal(err)
+	}
+	if readLocal(local, "site/new.html") != "remote only" {
+		t.Error("Remote files left alone by an upload-only sync should be found by a two-way sync")
+	}
+
+	if _, err := Sync(api, local, "/Web", twoWay); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Sync(api, local, "/Web", Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if readLocal(local, "cache/build.tmp") != "ignored" {
+		t.Error("Files that are no longer ignored should be synced")
+	}
+}
+
+func TestConflictName(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 30, 45, 0, time.UTC)
+	for name, expected := range map[string]string{
+		"docs/report.pdf": "docs/report (conflicted copy 2024-03-01 123045).pdf",
+		"Makefile":        "Makefile (conflicted copy 2024-03-01 123045)",
+		".bashrc":         ".bashrc (conflicted copy 2024-03-01 123045)",
+	} {
+		if actual := conflictName(name, now); actual != expected {
+			t.Errorf("Expected %q, got %q", expected, actual)
+		}
+	}
+	if !strings.HasSuffix(conflictName("a.tar.gz", now), ").gz") {
+		t.Error("Only the last extension should be kept")
+	}
+}
@@ -0,0 +1,65 @@
+package filesync
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// DefaultJournal is the name of the journal in the local folder when no other path is given
+const DefaultJournal = ".nextcloudgo-sync.json"
+
+// journalEntry is the state of a file after it was last synced
+type journalEntry struct {
+	IsDir bool   `json:"dir,omitempty"`
+	ETag  string `json:"etag"`
+	// Size and ModTime are the values of the local file
+	Size    int64 `json:"size"`
+	ModTime int64 `json:"mtime"`
+}
+
+// journal remembers the state of all synced files, keyed by their relative path.
+// The remote root is stored with the empty key.
+type journal struct {
+	Remote string `json:"remote"`
+	// Ignore are the ignore patterns of the sync, folder ETags are only trusted with the same patterns
+	Ignore  []string                `json:"ignore,omitempty"`
+	Entries map[string]journalEntry `json:"entries"`
+}
+
+// loadJournal reads the journal, a missing journal or one of another remote folder is empty
+func loadJournal(name, remote string) (journal, error) {
+	j := journal{Remote: remote, Entries: map[string]journalEntry{}}
+
+	data, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return j, err
+	}
+
+	var saved journal
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return j, err
+	}
+	if saved.Remote != remote || saved.Entries == nil {
+		return j, nil
+	}
+	return saved, nil
+}
+
+// save replaces the journal file atomically
+func (j journal) save(name string) error {
+	data, err := json.MarshalIndent(j, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(filepath.Dir(name), "."+filepath.Base(name)+partSuffix)
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
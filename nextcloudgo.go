@@ -1,26 +1,31 @@
 package nextcloudgo
 
 import (
-	"crypto/tls"
-	"crypto/x509"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
-
-	//"fmt"
-	"io/ioutil"
-	"log"
 	"net/http"
+	"strconv"
+	"sync"
 )
 
-//
+// NextcloudGo holds the server and login details of a Nextcloud instance.
+// Use New to configure the HTTP client, a struct literal builds a default client on its
+// first request. Either way the client is set up once and shared by all requests.
 type NextcloudGo struct {
 	ServerURL string
 	CertPath  string
 	User      string
 	Password  string
+
+	client *http.Client
+	info   *serverInfo
 }
 
+// lazyMu guards the fields that clients created as struct literal set on first use
+var lazyMu sync.Mutex
+
 // Status object for the server which mirrors the status.php content
 type Status struct {
 	// Installed reflects the installation state of the server
@@ -38,6 +43,8 @@ var (
 	ErrNotConnected = errors.New("Not connected to any server")
 	// ErrNoUserOrPassword is returned when the api has no user and/or password set.
 	ErrNoUserOrPassword = errors.New("No user/password given")
+	// ErrInvalidCertificate is returned when the CA bundle does not contain any PEM certificate.
+	ErrInvalidCertificate = errors.New("No valid certificate found in CA bundle")
 )
 
 func (nc *NextcloudGo) isConnected() bool {
@@ -48,40 +55,46 @@ func (nc *NextcloudGo) isLoggedIn() bool {
 	return nc.isConnected() && nc.User != "" && nc.Password != ""
 }
 
-// Capabilities should be moved to ocs
-// TODO(nickvergessen) Move out of the base
+// Capabilities returns the decoded JSON of the capabilities endpoint, nil when it
+// can not be requested or no user and password are set.
+//
+// Deprecated: Use ocs.Request.Capabilities, which returns typed capabilities and the error.
 func (nc *NextcloudGo) Capabilities() interface{} {
-	//capabilities := make(map[string]string)
+	return nc.CapabilitiesContext(context.Background())
+}
+
+// CapabilitiesContext is like Capabilities but aborts the request when ctx is done
+//
+// Deprecated: Use ocs.Request.CapabilitiesContext, which returns typed capabilities and the error.
+func (nc *NextcloudGo) CapabilitiesContext(ctx context.Context) interface{} {
 	var capabilities interface{}
 	if !nc.isLoggedIn() {
 		return capabilities
 	}
 
-	response, err := nc.Request(http.MethodGet, "/ocs/v1.php/cloud/capabilities?format=json", nil, true)
+	response, err := nc.RequestContext(ctx, http.MethodGet, "/ocs/v1.php/cloud/capabilities?format=json", nil, true)
 	if err != nil {
-		log.Fatal(err)
 		return capabilities
 	}
-
 	defer response.Body.Close()
-	contents, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		log.Fatal(err)
-		return capabilities
-	}
 
-	json.Unmarshal(contents, &capabilities)
+	json.NewDecoder(response.Body).Decode(&capabilities)
 	return capabilities
 }
 
 // Status returns the Status of the server
 func (nc *NextcloudGo) Status() (Status, error) {
+	return nc.StatusContext(context.Background())
+}
+
+// StatusContext is like Status but aborts the request when ctx is done
+func (nc *NextcloudGo) StatusContext(ctx context.Context) (Status, error) {
 	status := Status{Maintenance: true}
 	if !nc.isConnected() {
 		return status, ErrNotConnected
 	}
 
-	response, err := nc.Request(http.MethodGet, "/status.php", nil, false)
+	response, err := nc.RequestContext(ctx, http.MethodGet, "/status.php", nil, false)
 	if err != nil {
 		return status, err
 	}
@@ -97,7 +110,13 @@ func (nc *NextcloudGo) Status() (Status, error) {
 // Content-Type and everything else. But in general you should not need to use this
 // method yourself.
 func (nc *NextcloudGo) Request(method, url string, body io.Reader, auth bool) (*http.Response, error) {
-	req, err := http.NewRequest(method, nc.ServerURL+url, body)
+	return nc.RequestContext(context.Background(), method, url, body, auth)
+}
+
+// RequestContext is like Request but carries the given context to the http.Client,
+// so the request is aborted when ctx is cancelled or its deadline is exceeded.
+func (nc *NextcloudGo) RequestContext(ctx context.Context, method, url string, body io.Reader, auth bool) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, nc.ServerURL+url, body)
 
 	if err != nil {
 		return nil, err
@@ -105,30 +124,70 @@ func (nc *NextcloudGo) Request(method, url string, body io.Reader, auth bool) (*
 
 	if auth {
 		if !nc.isLoggedIn() {
-			return nil, errors.New(nc.ServerURL) //ErrNoUserOrPassword
+			return nil, ErrNoUserOrPassword
 		}
 		req.SetBasicAuth(nc.User, nc.Password)
 	}
 	req.Header.Add("OCS-APIRequest", "true")
+	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-Type", "application/json; charset=utf-8")
 
-	client := &http.Client{}
-	if nc.CertPath != "" {
-		tlsConfig := &tls.Config{}
-		certs := x509.NewCertPool()
+	client, err := nc.httpClient()
+	if err != nil {
+		return nil, err
+	}
 
-		pemData, err := ioutil.ReadFile(nc.CertPath)
-		if err != nil {
-			// do error
-		}
-		certs.AppendCertsFromPEM(pemData)
-		tlsConfig.RootCAs = certs
+	return client.Do(req)
+}
 
-		tr := &http.Transport{
-			TLSClientConfig: tlsConfig,
-		}
-		client = &http.Client{Transport: tr}
+// DAVRequest performs an authenticated WebDAV request to the given url.
+// Unlike Request it sends no OCS headers and leaves the Content-Type to the
+// given header, so bodies are streamed as they are.
+func (nc *NextcloudGo) DAVRequest(method, url string, body io.Reader, header http.Header) (*http.Response, error) {
+	return nc.DAVRequestContext(context.Background(), method, url, body, header)
+}
+
+// DAVRequestContext is like DAVRequest but aborts the request when ctx is done
+func (nc *NextcloudGo) DAVRequestContext(ctx context.Context, method, url string, body io.Reader, header http.Header) (*http.Response, error) {
+	if !nc.isLoggedIn() {
+		return nil, ErrNoUserOrPassword
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, nc.ServerURL+url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if req.ContentLength <= 0 && header.Get("Content-Length") != "" {
+		req.ContentLength, _ = strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	}
+	req.SetBasicAuth(nc.User, nc.Password)
+
+	client, err := nc.httpClient()
+	if err != nil {
+		return nil, err
 	}
 
 	return client.Do(req)
 }
+
+// httpClient returns the client built by New. A NextcloudGo that was created
+// as a struct literal builds its client on the first request and keeps it.
+func (nc *NextcloudGo) httpClient() (*http.Client, error) {
+	lazyMu.Lock()
+	defer lazyMu.Unlock()
+	if nc.client != nil {
+		return nc.client, nil
+	}
+
+	config := clientConfig{certPath: nc.CertPath}
+	client, err := config.build()
+	if err != nil {
+		return nil, err
+	}
+	nc.client = client
+	return client, nil
+}
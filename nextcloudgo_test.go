@@ -1,10 +1,16 @@
 package nextcloudgo
 
 import (
+	"context"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestConnect(t *testing.T) {
@@ -85,3 +91,259 @@ func TestStatusFailed(t *testing.T) {
 		t.Error("Version should be empty")
 	}
 }
+
+func TestStatusContextDeadline(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	nc := NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"}
+	_, err := nc.StatusContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Should receive context.DeadlineExceeded from a slow server, got %v", err)
+	}
+}
+
+func TestNewInvalidCertificate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := New("https://cloud.example.com", "admin", "admin", WithCACertFile(path))
+	if err != ErrInvalidCertificate {
+		t.Errorf("Should receive ErrInvalidCertificate for a broken CA bundle, got %v", err)
+	}
+
+	nc := NextcloudGo{ServerURL: "https://cloud.example.com", CertPath: path}
+	_, err = nc.Status()
+	if err != ErrInvalidCertificate {
+		t.Errorf("Should receive ErrInvalidCertificate for a broken CertPath, got %v", err)
+	}
+}
+
+func TestNewWithCACertFile(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, `{"installed":true,"maintenance":false,"version":"13.0.0.6","versionstring":"13.0.0 Beta 1"}`)
+	}))
+	defer ts.Close()
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := os.WriteFile(path, pemData, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	nc, err := New(ts.URL+"/", "admin", "admin", WithCACertFile(path), WithTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nc.ServerURL != ts.URL {
+		t.Error("Trailing slash should be removed from the server URL")
+	}
+
+	s, err := nc.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Version != "13.0.0.6" {
+		t.Error("Version was not extracted correctly")
+	}
+
+	// Struct literals read the CA bundle once and keep their client
+	literal := NextcloudGo{ServerURL: ts.URL, CertPath: path}
+	if _, err := literal.Status(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := literal.Status(); err != nil {
+		t.Errorf("The client should be reused, got %v", err)
+	}
+}
+
+type countingTransport struct {
+	requests int
+}
+
+func (ct *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ct.requests++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestNewWithTransport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"installed":true,"maintenance":false,"version":"13.0.0.6","versionstring":"13.0.0 Beta 1"}`)
+	}))
+	defer ts.Close()
+
+	transport := &countingTransport{}
+	nc, err := New(ts.URL, "admin", "admin", WithTransport(transport))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := nc.Status(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if transport.requests != 2 {
+		t.Errorf("Expected both requests to use the given transport, got %d", transport.requests)
+	}
+
+	_, err = New(ts.URL, "admin", "admin", WithTransport(transport), WithProxyURL("http://proxy.example.com:3128"))
+	if err == nil {
+		t.Error("Should receive an error when combining a custom RoundTripper with a proxy")
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	for version, expected := range map[string]Version{
+		"13.0.0.6":      {13, 0, 0, 6},
+		"27.1.3":        {27, 1, 3, 0},
+		"28":            {28, 0, 0, 0},
+		"13.0.0 Beta 1": {13, 0, 0, 0},
+	} {
+		v, err := ParseVersion(version)
+		if err != nil || v != expected {
+			t.Errorf("%q: expected %v, got %v (%v)", version, expected, v, err)
+		}
+	}
+
+	for _, version := range []string{"", "a.b", "1.2.3.4.5"} {
+		if _, err := ParseVersion(version); err == nil {
+			t.Errorf("%q should be rejected", version)
+		}
+	}
+
+	if !(Version{Major: 27, Minor: 1}).AtLeast(Version{Major: 27}) || (Version{Major: 20, Build: 9}).AtLeast(Version{Major: 20, Minor: 1}) {
+		t.Error("Versions were not compared correctly")
+	}
+}
+
+func TestSupports(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintln(w, `{"installed":true,"maintenance":false,"version":"24.0.12.1","versionstring":"24.0.12"}`)
+	}))
+	defer ts.Close()
+
+	nc, err := New(ts.URL, "admin", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := nc.Supports(FeatureGroupDisplayName); err != nil || !ok {
+		t.Error("Nextcloud 24 should support group display names")
+	}
+	if err := nc.Require(FeatureChunkingV2); !errors.Is(err, ErrUnsupportedByServer) {
+		t.Errorf("Expected ErrUnsupportedByServer, got %v", err)
+	}
+	if _, err := nc.Supports(Feature("unknown")); err == nil {
+		t.Error("Unknown features should be rejected")
+	}
+	if requests != 1 {
+		t.Errorf("The server version should be cached, got %d requests", requests)
+	}
+
+	nc, _ = New(ts.URL, "admin", "admin", WithServerVersion("29.0.0"))
+	if err := nc.Require(FeatureChunkingV2); err != nil {
+		t.Error(err)
+	}
+	if requests != 1 {
+		t.Error("The given server version should be used without a request")
+	}
+}
+
+func TestServerVersionFallback(t *testing.T) {
+	var requests []string
+	blocked := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		switch {
+		case r.URL.Path == "/status.php" && blocked:
+			w.WriteHeader(http.StatusForbidden)
+		case r.URL.Path == "/status.php":
+			fmt.Fprintln(w, `{"installed":true,"maintenance":false,"version":"21.0.9.1"}`)
+		case r.URL.Path == "/ocs/v2.php/cloud/capabilities":
+			fmt.Fprintln(w, `{"ocs":{"meta":{"status":"ok","statuscode":200},"data":{"version":{"major":28,"minor":0,"micro":4,"string":"28.0.4"}}}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	// Struct literals look up the version like clients of New and cache it themselves
+	nc := NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"}
+	if err := nc.Require(FeatureChunkingV2); err != nil {
+		t.Error(err)
+	}
+	version, err := nc.ServerVersion()
+	if err != nil || version != (Version{Major: 28, Patch: 4}) {
+		t.Errorf("The version should be read from the capabilities, got %v %v", version, err)
+	}
+	if err := nc.Require(Feature("unknown")); err == nil {
+		t.Error("Unknown features should be rejected")
+	}
+	if len(requests) != 2 {
+		t.Errorf("The version should be cached by the client, got %v", requests)
+	}
+	other := NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"}
+	if _, err := other.ServerVersion(); err != nil || len(requests) != 4 {
+		t.Errorf("Other clients should not share the cache, got %v %v", err, requests)
+	}
+
+	// Failed lookups are reported and cached until they expire
+	requests = nil
+	nc, _ = New(ts.URL, "", "")
+	if err := nc.Require(FeatureChunkingV2); err == nil || errors.Is(err, ErrUnsupportedByServer) {
+		t.Errorf("Expected the error of the lookup, got %v", err)
+	}
+	if _, err := nc.ServerVersion(); err == nil || len(requests) != 1 {
+		t.Errorf("The failure should be cached, got %v %v", err, requests)
+	}
+
+	blocked = false
+	nc.info.expires = time.Now()
+	if err := nc.Require(FeatureChunkingV2); !errors.Is(err, ErrUnsupportedByServer) {
+		t.Errorf("Expected ErrUnsupportedByServer, got %v", err)
+	}
+
+	// An expired version is kept when the server can not be asked again
+	blocked = true
+	nc.info.expires = time.Now()
+	if version, err := nc.ServerVersion(); err != nil || version.Major != 21 {
+		t.Errorf("Expected the expired version, got %v %v", version, err)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"ocs":{"meta":{"status":"ok","statuscode":100},"data":{"version":{"major":28},"capabilities":{"core":{"pollinterval":60}}}}}`)
+	}))
+	defer ts.Close()
+
+	nc := NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"}
+	capabilities, ok := nc.Capabilities().(map[string]interface{})
+	if !ok || capabilities["ocs"] == nil {
+		t.Errorf("Unexpected capabilities %v", nc.Capabilities())
+	}
+
+	nc.Password = ""
+	if capabilities := nc.Capabilities(); capabilities != nil {
+		t.Errorf("Expected no capabilities without login, got %v", capabilities)
+	}
+}
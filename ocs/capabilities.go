@@ -0,0 +1,247 @@
+package ocs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// ServerVersion is the version block of the capabilities
+type ServerVersion struct {
+	Major           int    `json:"major"`
+	Minor           int    `json:"minor"`
+	Micro           int    `json:"micro"`
+	String          string `json:"string"`
+	Edition         string `json:"edition"`
+	ExtendedSupport bool   `json:"extendedSupport"`
+}
+
+// CoreCapabilities are provided by the server itself
+type CoreCapabilities struct {
+	// PollInterval is the number of seconds clients should wait between polling for changes
+	PollInterval int    `json:"pollinterval"`
+	WebDAVRoot   string `json:"webdav-root"`
+}
+
+// FilesCapabilities are provided by the files app
+type FilesCapabilities struct {
+	BigFileChunking  bool     `json:"bigfilechunking"`
+	BlacklistedFiles []string `json:"blacklisted_files"`
+	Undelete         bool     `json:"undelete"`
+	Versioning       bool     `json:"versioning"`
+	VersionLabeling  bool     `json:"version_labeling"`
+	VersionDeletion  bool     `json:"version_deletion"`
+}
+
+// Enabled is a capability that is only switched on or off
+type Enabled struct {
+	Enabled bool `json:"enabled"`
+}
+
+// ExpireDate describes whether an expiration date is enabled and enforced
+type ExpireDate struct {
+	Enabled  bool `json:"enabled"`
+	Days     int  `json:"days"`
+	Enforced bool `json:"enforced"`
+}
+
+// PublicSharingCapabilities describe the link shares
+type PublicSharingCapabilities struct {
+	Enabled  bool `json:"enabled"`
+	Password struct {
+		Enforced               bool `json:"enforced"`
+		AskForOptionalPassword bool `json:"askForOptionalPassword"`
+	} `json:"password"`
+	ExpireDate         ExpireDate `json:"expire_date"`
+	ExpireDateInternal ExpireDate `json:"expire_date_internal"`
+	ExpireDateRemote   ExpireDate `json:"expire_date_remote"`
+	MultipleLinks      bool       `json:"multiple_links"`
+	SendMail           bool       `json:"send_mail"`
+	Upload             bool       `json:"upload"`
+	UploadFilesDrop    bool       `json:"upload_files_drop"`
+}
+
+// FilesSharingCapabilities are provided by the files_sharing app
+type FilesSharingCapabilities struct {
+	APIEnabled         bool                      `json:"api_enabled"`
+	Public             PublicSharingCapabilities `json:"public"`
+	Resharing          bool                      `json:"resharing"`
+	GroupSharing       bool                      `json:"group_sharing"`
+	DefaultPermissions int                       `json:"default_permissions"`
+	User               struct {
+		SendMail   bool       `json:"send_mail"`
+		ExpireDate ExpireDate `json:"expire_date"`
+	} `json:"user"`
+	Group struct {
+		Enabled    bool       `json:"enabled"`
+		ExpireDate ExpireDate `json:"expire_date"`
+	} `json:"group"`
+	Federation struct {
+		Outgoing   bool       `json:"outgoing"`
+		Incoming   bool       `json:"incoming"`
+		ExpireDate ExpireDate `json:"expire_date"`
+	} `json:"federation"`
+	ShareByMail *struct {
+		Enabled            bool    `json:"enabled"`
+		SendPasswordByMail bool    `json:"send_password_by_mail"`
+		UploadFilesDrop    Enabled `json:"upload_files_drop"`
+		Password           struct {
+			Enabled  bool `json:"enabled"`
+			Enforced bool `json:"enforced"`
+		} `json:"password"`
+		ExpireDate ExpireDate `json:"expire_date"`
+	} `json:"sharebymail"`
+}
+
+// NotificationsCapabilities are provided by the notifications app
+type NotificationsCapabilities struct {
+	OCSEndpoints       []string `json:"ocs-endpoints"`
+	Push               []string `json:"push"`
+	AdminNotifications []string `json:"admin-notifications"`
+}
+
+// SpreedCapabilities are provided by the Talk app
+type SpreedCapabilities struct {
+	Features []string `json:"features"`
+	Version  string   `json:"version"`
+	// Config is kept raw, because it changes with every Talk release
+	Config json.RawMessage `json:"config"`
+}
+
+// HasFeature returns true when Talk announces the given feature
+func (s *SpreedCapabilities) HasFeature(feature string) bool {
+	for _, f := range s.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// ThemingCapabilities are provided by the theming app
+type ThemingCapabilities struct {
+	Name               string `json:"name"`
+	URL                string `json:"url"`
+	Slogan             string `json:"slogan"`
+	Color              string `json:"color"`
+	ColorText          string `json:"color-text"`
+	ColorElement       string `json:"color-element"`
+	ColorElementBright string `json:"color-element-bright"`
+	ColorElementDark   string `json:"color-element-dark"`
+	Logo               string `json:"logo"`
+	Background         string `json:"background"`
+	BackgroundPlain    bool   `json:"background-plain"`
+	BackgroundDefault  bool   `json:"background-default"`
+	LogoHeader         string `json:"logoheader"`
+	Favicon            string `json:"favicon"`
+}
+
+// PasswordPolicyCapabilities are provided by the password_policy app
+type PasswordPolicyCapabilities struct {
+	MinLength                int  `json:"minLength"`
+	EnforceNonCommonPassword bool `json:"enforceNonCommonPassword"`
+	EnforceNumericCharacters bool `json:"enforceNumericCharacters"`
+	EnforceSpecialCharacters bool `json:"enforceSpecialCharacters"`
+	EnforceUpperLowerCase    bool `json:"enforceUpperLowerCase"`
+	API                      struct {
+		Generate string `json:"generate"`
+		Validate string `json:"validate"`
+	} `json:"api"`
+}
+
+// UserStatusCapabilities are provided by the user_status app
+type UserStatusCapabilities struct {
+	Enabled       bool `json:"enabled"`
+	Restore       bool `json:"restore"`
+	SupportsEmoji bool `json:"supports_emoji"`
+}
+
+// Capabilities of the server and its apps.
+// The capabilities of apps are nil when the app is not enabled
+// or sent capabilities in an unexpected format.
+type Capabilities struct {
+	Version        ServerVersion
+	Core           CoreCapabilities
+	Files          *FilesCapabilities
+	FilesSharing   *FilesSharingCapabilities
+	Notifications  *NotificationsCapabilities
+	Spreed         *SpreedCapabilities
+	Theming        *ThemingCapabilities
+	PasswordPolicy *PasswordPolicyCapabilities
+	UserStatus     *UserStatusCapabilities
+	// Raw holds the capabilities of all apps keyed by app id, including unknown ones
+	Raw map[string]json.RawMessage
+}
+
+// UnmarshalJSON decodes the data of the capabilities endpoint
+func (c *Capabilities) UnmarshalJSON(b []byte) error {
+	var data struct {
+		Version      ServerVersion              `json:"version"`
+		Capabilities map[string]json.RawMessage `json:"capabilities"`
+	}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	*c = Capabilities{Version: data.Version, Raw: data.Capabilities}
+	if c.Raw == nil {
+		c.Raw = map[string]json.RawMessage{}
+	}
+
+	if raw, ok := c.Raw["core"]; ok {
+		if err := json.Unmarshal(raw, &c.Core); err != nil {
+			return err
+		}
+	}
+	c.Files = decodeApp[FilesCapabilities](c.Raw, "files")
+	c.FilesSharing = decodeApp[FilesSharingCapabilities](c.Raw, "files_sharing")
+	c.Notifications = decodeApp[NotificationsCapabilities](c.Raw, "notifications")
+	c.Spreed = decodeApp[SpreedCapabilities](c.Raw, "spreed")
+	c.Theming = decodeApp[ThemingCapabilities](c.Raw, "theming")
+	c.PasswordPolicy = decodeApp[PasswordPolicyCapabilities](c.Raw, "password_policy")
+	c.UserStatus = decodeApp[UserStatusCapabilities](c.Raw, "user_status")
+	return nil
+}
+
+// decodeApp returns the typed capabilities of an app, or nil when they are
+// missing or differ from the expected format. Raw still holds them in that case.
+func decodeApp[T any](raw map[string]json.RawMessage, app string) *T {
+	data, ok := raw[app]
+	if !ok {
+		return nil
+	}
+
+	v := new(T)
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil
+	}
+	return v
+}
+
+// App decodes the raw capabilities of the given app into v
+// Returns false when the app has no capabilities
+func (c *Capabilities) App(app string, v interface{}) (bool, error) {
+	raw, ok := c.Raw[app]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, v)
+}
+
+// Capabilities returns the capabilities of the server and its apps.
+// Without user and password only the public capabilities are returned.
+func (ocs *Request) Capabilities() (Capabilities, error) {
+	return ocs.CapabilitiesContext(context.Background())
+}
+
+// CapabilitiesContext is like Capabilities but aborts the request when ctx is done
+func (ocs *Request) CapabilitiesContext(ctx context.Context) (Capabilities, error) {
+	auth := ocs.nc.User != "" && ocs.nc.Password != ""
+
+	res, err := DoContext[Capabilities](ctx, ocs, http.MethodGet, "/ocs/v2.php/cloud/capabilities?format=json", nil, auth)
+	if err != nil {
+		return Capabilities{}, err
+	}
+
+	return res.Data, nil
+}
@@ -1,6 +1,7 @@
 package ocs
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -21,12 +22,22 @@ func New(nc nextcloudgo.NextcloudGo) Request {
 
 // Request performs an (authenticated) request and returns the data
 func (ocs *Request) Request(method, url string, auth bool) (map[string]interface{}, int, error) {
-	return ocs.RequestWithBody(method, url, nil, auth)
+	return ocs.RequestWithBodyContext(context.Background(), method, url, nil, auth)
+}
+
+// RequestContext is like Request but aborts the request when ctx is done
+func (ocs *Request) RequestContext(ctx context.Context, method, url string, auth bool) (map[string]interface{}, int, error) {
+	return ocs.RequestWithBodyContext(ctx, method, url, nil, auth)
 }
 
 // RequestWithBody performs an (authenticated) request and returns the data
 func (ocs *Request) RequestWithBody(method, url string, body io.Reader, auth bool) (map[string]interface{}, int, error) {
-	response, err := ocs.nc.Request(method, url, body, auth)
+	return ocs.RequestWithBodyContext(context.Background(), method, url, body, auth)
+}
+
+// RequestWithBodyContext is like RequestWithBody but aborts the request when ctx is done
+func (ocs *Request) RequestWithBodyContext(ctx context.Context, method, url string, body io.Reader, auth bool) (map[string]interface{}, int, error) {
+	response, err := ocs.nc.RequestContext(ctx, method, url, body, auth)
 	if response == nil {
 		return nil, 400, err
 	}
@@ -42,7 +53,9 @@ func (ocs *Request) RequestWithBody(method, url string, body io.Reader, auth boo
 	}
 
 	var mixed interface{}
-	json.Unmarshal(contents, &mixed)
+	if err := json.Unmarshal(contents, &mixed); err != nil {
+		return nil, response.StatusCode, errors.New("Invalid JSON response")
+	}
 
 	data, ok := mixed.(map[string]interface{})
 	if !ok {
@@ -52,6 +65,8 @@ func (ocs *Request) RequestWithBody(method, url string, body io.Reader, auth boo
 }
 
 // ValidateStatusCode checks whether the OCS status code matches the accepted value
+//
+// Deprecated: Use Do, which returns an *Error holding the status code.
 func ValidateStatusCode(data map[string]interface{}, accepted int) bool {
 	status, err := GetInt(data, []string{"ocs", "meta", "statuscode"})
 	if err != nil {
@@ -61,6 +76,8 @@ func ValidateStatusCode(data map[string]interface{}, accepted int) bool {
 }
 
 // GetInt returns a single int from a given subtree in the OCS response
+//
+// Deprecated: Use Do, which decodes the data into a typed struct.
 func GetInt(data map[string]interface{}, keys []string) (int, error) {
 	var ok bool
 	var element float64
@@ -84,6 +101,8 @@ func GetInt(data map[string]interface{}, keys []string) (int, error) {
 }
 
 // GetStringList returns a string array from a given subtree in the OCS response
+//
+// Deprecated: Use Do, which decodes the data into a typed struct.
 func GetStringList(data map[string]interface{}, keys []string) ([]string, error) {
 	var elements []interface{}
 	var ok bool
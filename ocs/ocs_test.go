@@ -0,0 +1,168 @@
+package ocs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nextcloud/nextcloudgo"
+)
+
+type userData struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayname"`
+}
+
+func TestDo(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, `{"ocs":{"meta":{"status":"ok","statuscode":200,"message":"OK"},"data":{"id":"admin","displayname":"Administrator"}}}`)
+	}))
+	defer ts.Close()
+
+	nc := nextcloudgo.NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"}
+	request := New(nc)
+
+	res, err := Do[userData](&request, http.MethodGet, "/ocs/v2.php/cloud/user", nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Meta.StatusCode != 200 || res.HTTPStatus != http.StatusOK {
+		t.Error("Status codes were not extracted correctly")
+	}
+	if res.Data.ID != "admin" || res.Data.DisplayName != "Administrator" {
+		t.Error("Data was not decoded correctly")
+	}
+}
+
+func TestDoEmptyData(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, `{"ocs":{"meta":{"status":"ok","statuscode":100,"message":"OK"},"data":[]}}`)
+	}))
+	defer ts.Close()
+
+	nc := nextcloudgo.NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"}
+	request := New(nc)
+
+	res, err := Do[userData](&request, http.MethodGet, "/ocs/v1.php/cloud/user", nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Data.ID != "" {
+		t.Error("Empty data should decode into the zero value")
+	}
+}
+
+func TestDoError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/ocs/v1.php/cloud/users" {
+			fmt.Fprintln(w, `{"ocs":{"meta":{"status":"failure","statuscode":102,"message":"User already exists"},"data":[]}}`)
+			return
+		}
+		if r.URL.Path == "/ocs/v2.php/cloud/users" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, `{"ocs":{"meta":{"status":"failure","statuscode":102,"message":"User already exists"},"data":[]}}`)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	nc := nextcloudgo.NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"}
+	request := New(nc)
+
+	for _, tc := range []struct {
+		url        string
+		httpStatus int
+		statusCode int
+	}{
+		{"/ocs/v1.php/cloud/users", http.StatusOK, 102},
+		{"/ocs/v2.php/cloud/users", http.StatusBadRequest, 102},
+		{"/status.php", http.StatusInternalServerError, 0},
+	} {
+		_, err := Do[userData](&request, http.MethodPost, tc.url, nil, true)
+		var e *Error
+		if !errors.As(err, &e) {
+			t.Errorf("%s: expected an *Error, got %v", tc.url, err)
+			continue
+		}
+		if e.HTTPStatus != tc.httpStatus || e.StatusCode != tc.statusCode {
+			t.Errorf("%s: unexpected status codes %d/%d", tc.url, e.HTTPStatus, e.StatusCode)
+		}
+	}
+}
+
+func TestDoInvalidJSON(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `<html></html>`)
+	}))
+	defer ts.Close()
+
+	nc := nextcloudgo.NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"}
+	request := New(nc)
+
+	_, err := Do[userData](&request, http.MethodGet, "/ocs/v2.php/cloud/user", nil, true)
+	var e *Error
+	if err == nil || errors.As(err, &e) {
+		t.Errorf("Expected a decoding error, got %v", err)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/ocs/v2.php/cloud/capabilities" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintln(w, `{"ocs":{"meta":{"status":"ok","statuscode":200,"message":"OK"},"data":{`+
+			`"version":{"major":27,"minor":1,"micro":3,"string":"27.1.3","edition":"","extendedSupport":false},`+
+			`"capabilities":{"core":{"pollinterval":60,"webdav-root":"remote.php/webdav"},`+
+			`"files":{"bigfilechunking":true,"blacklisted_files":[".htaccess"],"undelete":true,"versioning":true},`+
+			`"files_sharing":{"api_enabled":true,"public":{"enabled":true,"password":{"enforced":true},"expire_date":{"enabled":false}},"resharing":true,"default_permissions":31,"federation":{"outgoing":true,"incoming":false}},`+
+			`"notifications":{"ocs-endpoints":["list","get"]},`+
+			`"spreed":{"features":["audio","video","chat-v2"],"config":{"chat":{"max-length":32000}},"version":"17.1.3"},`+
+			`"theming":{"name":"Nextcloud","color":"#0082c9","background-plain":false},`+
+			`"password_policy":{"minLength":10,"enforceNonCommonPassword":true},`+
+			`"user_status":{"enabled":true,"supports_emoji":true},`+
+			`"weather_status":{"enabled":true}}}}}`)
+	}))
+	defer ts.Close()
+
+	nc := nextcloudgo.NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"}
+	request := New(nc)
+
+	c, err := request.Capabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Version.Major != 27 || c.Version.String != "27.1.3" || c.Core.PollInterval != 60 {
+		t.Errorf("Version or core were not decoded correctly: %+v %+v", c.Version, c.Core)
+	}
+	if c.Files == nil || !c.Files.Versioning || c.FilesSharing == nil || !c.FilesSharing.Public.Password.Enforced || c.FilesSharing.Federation.Incoming {
+		t.Error("Files capabilities were not decoded correctly")
+	}
+	if c.Spreed == nil || !c.Spreed.HasFeature("chat-v2") || c.Spreed.HasFeature("federation-v1") {
+		t.Error("Talk capabilities were not decoded correctly")
+	}
+	if c.Theming == nil || c.Theming.Color != "#0082c9" || c.PasswordPolicy == nil || c.PasswordPolicy.MinLength != 10 {
+		t.Error("Theming or password policy were not decoded correctly")
+	}
+	if c.Notifications == nil || c.UserStatus == nil || !c.UserStatus.SupportsEmoji {
+		t.Error("Notifications or user status were not decoded correctly")
+	}
+
+	var weather struct {
+		Enabled bool `json:"enabled"`
+	}
+	if ok, err := c.App("weather_status", &weather); !ok || err != nil || !weather.Enabled {
+		t.Error("Unknown app capabilities should be available raw")
+	}
+	if ok, _ := c.App("deck", &weather); ok {
+		t.Error("Missing app capabilities should not be reported")
+	}
+}
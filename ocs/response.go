@@ -0,0 +1,120 @@
+package ocs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Meta is the meta block every OCS response carries next to the data
+type Meta struct {
+	// Status is either "ok" or "failure"
+	Status string `json:"status"`
+	// StatusCode is the OCS status code, 100 (v1) or 200 (v2) on success
+	StatusCode int `json:"statuscode"`
+	// Message is the optional message of the server, mostly set on failures
+	Message string `json:"message"`
+	// TotalItems is only set by endpoints that support paging
+	TotalItems string `json:"totalitems"`
+	// ItemsPerPage is only set by endpoints that support paging
+	ItemsPerPage string `json:"itemsperpage"`
+}
+
+// Response is the OCS envelope with ocs.data decoded into T
+type Response[T any] struct {
+	// HTTPStatus is the status code of the HTTP response
+	HTTPStatus int
+	Meta       Meta
+	Data       T
+}
+
+// Error is returned when the server answered, but the request failed.
+// Use errors.As to get access to it.
+type Error struct {
+	// HTTPStatus is the status code of the HTTP response
+	HTTPStatus int
+	// StatusCode is the OCS status code, or 0 when the response was no OCS response
+	StatusCode int
+	// Message is the message of the server, or the HTTP status text as a fallback
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("OCS request failed with status %d (HTTP %d): %s", e.StatusCode, e.HTTPStatus, e.Message)
+}
+
+type envelope struct {
+	OCS struct {
+		Meta Meta            `json:"meta"`
+		Data json.RawMessage `json:"data"`
+	} `json:"ocs"`
+}
+
+// Do performs an (authenticated) request and decodes the OCS data into T
+// Returns an *Error when the HTTP or OCS status code reports a failure
+func Do[T any](ocs *Request, method, url string, body io.Reader, auth bool) (Response[T], error) {
+	return DoContext[T](context.Background(), ocs, method, url, body, auth)
+}
+
+// DoContext is like Do but aborts the request when ctx is done
+func DoContext[T any](ctx context.Context, ocs *Request, method, url string, body io.Reader, auth bool) (Response[T], error) {
+	res := Response[T]{}
+
+	response, err := ocs.nc.RequestContext(ctx, method, url, body, auth)
+	if err != nil {
+		return res, err
+	}
+	defer response.Body.Close()
+	res.HTTPStatus = response.StatusCode
+
+	contents, err := io.ReadAll(response.Body)
+	if err != nil {
+		return res, err
+	}
+
+	var data envelope
+	if err := json.Unmarshal(contents, &data); err != nil || data.OCS.Meta.Status == "" {
+		if !isSuccess(response.StatusCode) {
+			return res, &Error{HTTPStatus: response.StatusCode, Message: http.StatusText(response.StatusCode)}
+		}
+		if err == nil {
+			err = fmt.Errorf("missing ocs.meta")
+		}
+		return res, fmt.Errorf("Invalid JSON response: %w", err)
+	}
+	res.Meta = data.OCS.Meta
+
+	if !isSuccess(response.StatusCode) || (res.Meta.StatusCode != 100 && res.Meta.StatusCode != 200) {
+		message := res.Meta.Message
+		if message == "" {
+			message = http.StatusText(response.StatusCode)
+		}
+		return res, &Error{HTTPStatus: response.StatusCode, StatusCode: res.Meta.StatusCode, Message: message}
+	}
+
+	if err := decodeData(data.OCS.Data, &res.Data); err != nil {
+		return res, fmt.Errorf("Invalid JSON response: %w", err)
+	}
+	return res, nil
+}
+
+// decodeData unmarshals the data block, but accepts the empty array
+// that the server sends for empty objects
+func decodeData(raw json.RawMessage, v interface{}) error {
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil
+	}
+
+	err := json.Unmarshal(raw, v)
+	if err != nil && bytes.Equal(bytes.TrimSpace(raw), []byte("[]")) {
+		return nil
+	}
+	return err
+}
+
+func isSuccess(status int) bool {
+	return status >= 200 && status < 300
+}
@@ -0,0 +1,293 @@
+package provisioning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nextcloud/nextcloudgo/ocs"
+)
+
+// Author of an app
+type Author struct {
+	Name     string
+	Email    string
+	Homepage string
+}
+
+// VersionRange is a dependency on a minimum and/or maximum version, both may be empty
+type VersionRange struct {
+	Min string
+	Max string
+}
+
+// AppDependencies lists the requirements of an app
+type AppDependencies struct {
+	Nextcloud VersionRange
+	PHP       VersionRange
+	// Raw holds all dependencies as sent by the server, e.g. "database" or "lib"
+	Raw map[string]json.RawMessage
+}
+
+// AppInfo holds the details of an app as described in its appinfo/info.xml
+type AppInfo struct {
+	ID           string
+	Name         string
+	Summary      string
+	Description  string
+	Version      string
+	Licence      string
+	Authors      []Author
+	Types        []string
+	Category     []string
+	Dependencies AppDependencies
+}
+
+// UnmarshalJSON decodes the app info, which the server converts from XML,
+// so most fields can either be a single value or a list of values
+func (a *AppInfo) UnmarshalJSON(b []byte) error {
+	var data struct {
+		ID           string                     `json:"id"`
+		Name         string                     `json:"name"`
+		Summary      json.RawMessage            `json:"summary"`
+		Description  json.RawMessage            `json:"description"`
+		Version      string                     `json:"version"`
+		Licence      json.RawMessage            `json:"licence"`
+		Author       json.RawMessage            `json:"author"`
+		Types        json.RawMessage            `json:"types"`
+		Category     json.RawMessage            `json:"category"`
+		Dependencies map[string]json.RawMessage `json:"dependencies"`
+	}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	*a = AppInfo{
+		ID:          data.ID,
+		Name:        data.Name,
+		Summary:     firstString(data.Summary),
+		Description: firstString(data.Description),
+		Version:     data.Version,
+		Licence:     firstString(data.Licence),
+		Types:       xmlList(data.Types),
+		Category:    xmlList(data.Category),
+		Dependencies: AppDependencies{
+			Nextcloud: versionRange(data.Dependencies["nextcloud"]),
+			PHP:       versionRange(data.Dependencies["php"]),
+			Raw:       data.Dependencies,
+		},
+	}
+
+	var authors []json.RawMessage
+	if err := json.Unmarshal(data.Author, &authors); err != nil {
+		authors = []json.RawMessage{data.Author}
+	}
+	for _, raw := range authors {
+		if author, ok := parseAuthor(raw); ok {
+			a.Authors = append(a.Authors, author)
+		}
+	}
+	return nil
+}
+
+// xmlAttributes is how the server encodes an XML element with attributes
+type xmlAttributes struct {
+	Attributes map[string]string `json:"@attributes"`
+	Value      string            `json:"@value"`
+}
+
+func parseAuthor(raw json.RawMessage) (Author, bool) {
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		return Author{Name: name}, name != ""
+	}
+
+	var element xmlAttributes
+	if err := json.Unmarshal(raw, &element); err != nil || element.Value == "" {
+		return Author{}, false
+	}
+	return Author{Name: element.Value, Email: element.Attributes["mail"], Homepage: element.Attributes["homepage"]}, true
+}
+
+func versionRange(raw json.RawMessage) VersionRange {
+	var element xmlAttributes
+	json.Unmarshal(raw, &element)
+	return VersionRange{Min: element.Attributes["min-version"], Max: element.Attributes["max-version"]}
+}
+
+// firstString returns a plain string or the first of a list of translations
+func firstString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil && len(list) > 0 {
+		return list[0]
+	}
+	var translations map[string]string
+	if err := json.Unmarshal(raw, &translations); err == nil {
+		if s, ok := translations["en"]; ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// xmlList returns a list of strings, a single string or the keys of an object
+func xmlList(raw json.RawMessage) []string {
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return []string{s}
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &object); err == nil {
+		for key := range object {
+			list = append(list, key)
+		}
+		sort.Strings(list)
+	}
+	return list
+}
+
+// GetAppInfo returns the details of the given app
+// Returns ErrAppDoesNotExist when the app does not exist
+func (api *Provisioning) GetAppInfo(appid string) (AppInfo, error) {
+	return api.GetAppInfoContext(context.Background(), appid)
+}
+
+// GetAppInfoContext is like GetAppInfo but aborts the request when ctx is done
+func (api *Provisioning) GetAppInfoContext(ctx context.Context, appid string) (AppInfo, error) {
+	url := endpoint + "/apps/" + appid + "?format=json"
+
+	res, err := ocs.DoContext[AppInfo](ctx, &api.ocs, http.MethodGet, url, nil, true)
+	if err != nil {
+		return AppInfo{}, wrapError("getting the app info", err, appErrors)
+	}
+
+	return res.Data, nil
+}
+
+// GetAppVersions returns the versions of all apps matching the given filter, keyed by app id
+// Valid values for filter are: enabled, disabled, all
+func (api *Provisioning) GetAppVersions(filter string) (map[string]string, error) {
+	return api.GetAppVersionsContext(context.Background(), filter)
+}
+
+// GetAppVersionsContext is like GetAppVersions but aborts the requests when ctx is done
+func (api *Provisioning) GetAppVersionsContext(ctx context.Context, filter string) (map[string]string, error) {
+	apps, err := api.GetAppsContext(ctx, filter)
+	if err != nil {
+		return map[string]string{}, err
+	}
+
+	versions := make(map[string]string, len(apps))
+	for _, app := range apps {
+		info, err := api.GetAppInfoContext(ctx, app)
+		if err != nil {
+			return map[string]string{}, err
+		}
+		versions[app] = info.Version
+	}
+	return versions, nil
+}
+
+// IsAppVersionAtLeast returns true when the version of the app is the same or newer than the given one
+// Returns ErrAppDoesNotExist when the app does not exist
+func (api *Provisioning) IsAppVersionAtLeast(appid, version string) (bool, error) {
+	return api.IsAppVersionAtLeastContext(context.Background(), appid, version)
+}
+
+// IsAppVersionAtLeastContext is like IsAppVersionAtLeast but aborts the request when ctx is done
+func (api *Provisioning) IsAppVersionAtLeastContext(ctx context.Context, appid, version string) (bool, error) {
+	info, err := api.GetAppInfoContext(ctx, appid)
+	if err != nil {
+		return false, err
+	}
+	return CompareVersions(info.Version, version) >= 0, nil
+}
+
+// CompareVersions compares two dotted version numbers like "1.15.0" and "1.2".
+// It returns -1 when a is older than b, 0 when they are equal and 1 when a is newer.
+// Missing parts count as 0 and pre-release suffixes like "-beta.1" are ignored.
+func CompareVersions(a, b string) int {
+	partsA := versionParts(a)
+	partsB := versionParts(b)
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var x, y int
+		if i < len(partsA) {
+			x = partsA[i]
+		}
+		if i < len(partsB) {
+			y = partsB[i]
+		}
+		if x < y {
+			return -1
+		}
+		if x > y {
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(version string) []int {
+	version, _, _ = strings.Cut(strings.TrimPrefix(strings.TrimSpace(version), "v"), "-")
+	var parts []int
+	for _, part := range strings.Split(version, ".") {
+		n, _ := strconv.Atoi(part)
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// EnableAppForGroups enables an app only for the members of the given groups
+// The provisioning API does not support this, so the request is sent to the endpoint
+// of the app settings, which may require a recent password confirmation of the admin.
+func (api *Provisioning) EnableAppForGroups(appid string, groups []string) error {
+	return api.EnableAppForGroupsContext(context.Background(), appid, groups)
+}
+
+// EnableAppForGroupsContext is like EnableAppForGroups but aborts the request when ctx is done
+func (api *Provisioning) EnableAppForGroupsContext(ctx context.Context, appid string, groups []string) error {
+	if len(groups) == 0 {
+		return api.EnableAppContext(ctx, appid)
+	}
+
+	body := map[string][]string{"appIds": {appid}, "groups": groups}
+	reader := new(bytes.Buffer)
+	json.NewEncoder(reader).Encode(body)
+
+	response, err := api.nc.RequestContext(ctx, http.MethodPost, "/index.php/settings/apps/enable", reader, true)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	contents, _ := io.ReadAll(response.Body)
+	var data struct {
+		Data struct {
+			Message string `json:"message"`
+		} `json:"data"`
+	}
+	json.Unmarshal(contents, &data)
+
+	e := &ocs.Error{HTTPStatus: response.StatusCode, Message: data.Data.Message}
+	if e.Message == "" {
+		e.Message = http.StatusText(response.StatusCode)
+	}
+	return wrapError("enabling the app", e, appErrors)
+}
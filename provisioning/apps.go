@@ -1,22 +1,27 @@
 package provisioning
 
 import (
-	"errors"
+	"context"
+	"encoding/json"
 	"net/http"
 
 	"github.com/nextcloud/nextcloudgo/ocs"
 )
 
-var (
-	// ErrAppDoesNotExist when the app does not exist
-	ErrAppDoesNotExist = errors.New("App does not exist")
-)
+type appList struct {
+	Apps []string `json:"apps"`
+}
 
 // GetApps returns the list of apps matching the given filter
 // Valid values for filter are: enabled, disabled, all
 func (api *Provisioning) GetApps(filter string) ([]string, error) {
+	return api.GetAppsContext(context.Background(), filter)
+}
+
+// GetAppsContext is like GetApps but aborts the request when ctx is done
+func (api *Provisioning) GetAppsContext(ctx context.Context, filter string) ([]string, error) {
 	if filter != "enabled" && filter != "disabled" && filter != "all" {
-		return []string{}, errors.New("Invalid filter given")
+		return []string{}, ErrInvalidFilter
 	}
 
 	url := endpoint + "/apps"
@@ -24,35 +29,46 @@ func (api *Provisioning) GetApps(filter string) ([]string, error) {
 		url = url + "?filter=" + filter
 	}
 
-	content, status, err := api.ocs.Request(http.MethodGet, url, true)
+	res, err := ocs.DoContext[appList](ctx, &api.ocs, http.MethodGet, url, nil, true)
 	if err != nil {
-		return []string{}, errors.New("An error occured while searching for apps")
-	}
-
-	if status != http.StatusOK {
-		return []string{}, errors.New("An error occured while searching for apps")
+		return []string{}, wrapError("searching for apps", err, getAppsErrors)
 	}
 
-	return ocs.GetStringList(content, []string{"ocs", "data", "apps"})
+	return res.Data.Apps, nil
 }
 
 // IsAppEnabled returns true when the app is enabled, false otherwise
 func (api *Provisioning) IsAppEnabled(appid string) (bool, error) {
-	return api.isAppInArray(appid, "enabled")
+	return api.IsAppEnabledContext(context.Background(), appid)
+}
+
+// IsAppEnabledContext is like IsAppEnabled but aborts the request when ctx is done
+func (api *Provisioning) IsAppEnabledContext(ctx context.Context, appid string) (bool, error) {
+	return api.isAppInArray(ctx, appid, "enabled")
 }
 
 // IsAppDisabled returns true when the app is disabled but available, false otherwise
 func (api *Provisioning) IsAppDisabled(appid string) (bool, error) {
-	return api.isAppInArray(appid, "disabled")
+	return api.IsAppDisabledContext(context.Background(), appid)
+}
+
+// IsAppDisabledContext is like IsAppDisabled but aborts the request when ctx is done
+func (api *Provisioning) IsAppDisabledContext(ctx context.Context, appid string) (bool, error) {
+	return api.isAppInArray(ctx, appid, "disabled")
 }
 
 // IsAppAvailable returns true when the app is available, false otherwise
 func (api *Provisioning) IsAppAvailable(appid string) (bool, error) {
-	return api.isAppInArray(appid, "all")
+	return api.IsAppAvailableContext(context.Background(), appid)
+}
+
+// IsAppAvailableContext is like IsAppAvailable but aborts the request when ctx is done
+func (api *Provisioning) IsAppAvailableContext(ctx context.Context, appid string) (bool, error) {
+	return api.isAppInArray(ctx, appid, "all")
 }
 
-func (api *Provisioning) isAppInArray(appid, filter string) (bool, error) {
-	apps, err := api.GetApps(filter)
+func (api *Provisioning) isAppInArray(ctx context.Context, appid, filter string) (bool, error) {
+	apps, err := api.GetAppsContext(ctx, filter)
 	if err != nil {
 		return false, err
 	}
@@ -69,34 +85,32 @@ func (api *Provisioning) isAppInArray(appid, filter string) (bool, error) {
 // EnableApp enables an app when it is available
 // Returns ErrAppDoesNotExist when the app does not exist
 func (api *Provisioning) EnableApp(appid string) error {
-	return api.changeAppState(appid, http.MethodPost)
+	return api.EnableAppContext(context.Background(), appid)
+}
+
+// EnableAppContext is like EnableApp but aborts the request when ctx is done
+func (api *Provisioning) EnableAppContext(ctx context.Context, appid string) error {
+	return api.changeAppState(ctx, appid, http.MethodPost)
 }
 
 // DisableApp disables an app when it is available
 // Returns ErrAppDoesNotExist when the app does not exist
 func (api *Provisioning) DisableApp(appid string) error {
-	return api.changeAppState(appid, http.MethodDelete)
+	return api.DisableAppContext(context.Background(), appid)
 }
 
-func (api *Provisioning) changeAppState(appid, method string) error {
-	url := endpoint + "/apps/" + appid
-
-	_, status, err := api.ocs.Request(method, url, true)
-	if err != nil {
-		return err
-	}
-
-	if status == http.StatusNotFound {
-		return ErrAppDoesNotExist
-	}
+// DisableAppContext is like DisableApp but aborts the request when ctx is done
+func (api *Provisioning) DisableAppContext(ctx context.Context, appid string) error {
+	return api.changeAppState(ctx, appid, http.MethodDelete)
+}
 
-	if status != http.StatusOK {
-		if method == http.MethodPost {
-			return errors.New("An error occured while enabling the app")
-		}
+func (api *Provisioning) changeAppState(ctx context.Context, appid, method string) error {
+	url := endpoint + "/apps/" + appid
 
-		return errors.New("An error occured while disabling the app")
+	_, err := ocs.DoContext[json.RawMessage](ctx, &api.ocs, method, url, nil, true)
+	if method == http.MethodPost {
+		return wrapError("enabling the app", err, appErrors)
 	}
 
-	return nil
+	return wrapError("disabling the app", err, appErrors)
 }
@@ -1,13 +1,18 @@
 package provisioning
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/nextcloud/nextcloudgo"
+	"github.com/nextcloud/nextcloudgo/ocs"
 )
 
 func TestGetList(t *testing.T) {
@@ -204,3 +209,142 @@ func TestIsAppAvailableError(t *testing.T) {
 		t.Error(err.Error())
 	}
 }
+
+func TestGetListContextCancelled(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	nc := nextcloudgo.NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"}
+	api := New(nc)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	_, err := api.GetAppsContext(ctx, "all")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Should receive context.Canceled on a cancelled request, got %v", err)
+	}
+}
+
+func TestGetAppInfo(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/ocs/v2.php/cloud/apps/spreed":
+			fmt.Fprintln(w, `{"ocs":{"meta":{"status":"ok","statuscode":200,"message":"OK"},"data":{"id":"spreed","name":"Talk","summary":"Chat, video & audio-conferencing using WebRTC","version":"17.1.3","licence":"agpl",`+
+				`"author":[{"@attributes":{"mail":"jane@example.com"},"@value":"Jane Doe"},"John Doe"],"types":["dav","prevent_group_restriction"],"category":["multimedia","social"],`+
+				`"dependencies":{"nextcloud":{"@attributes":{"min-version":"27","max-version":"27"}},"php":{"@attributes":{"min-version":"8.0"}}}}}}`)
+		case "/ocs/v2.php/cloud/apps/files":
+			fmt.Fprintln(w, `{"ocs":{"meta":{"status":"ok","statuscode":200,"message":"OK"},"data":{"id":"files","name":"Files","version":"1.22.0","author":"Robin Appelman","types":{"filesystem":[]},"category":"files","dependencies":{"nextcloud":{"@attributes":{"min-version":"27"}}}}}}`)
+		case "/ocs/v2.php/cloud/apps":
+			fmt.Fprintln(w, `{"ocs":{"meta":{"status":"ok","statuscode":200,"message":"OK"},"data":{"apps":["files","spreed"]}}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintln(w, `{"ocs":{"meta":{"status":"failure","statuscode":404,"message":"The request app was not found"},"data":[]}}`)
+		}
+	}))
+	defer ts.Close()
+
+	nc := nextcloudgo.NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"}
+	api := New(nc)
+
+	info, err := api.GetAppInfo("spreed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "Talk" || info.Version != "17.1.3" || info.Licence != "agpl" {
+		t.Errorf("App info was not decoded correctly: %+v", info)
+	}
+	if !reflect.DeepEqual(info.Authors, []Author{{Name: "Jane Doe", Email: "jane@example.com"}, {Name: "John Doe"}}) {
+		t.Errorf("Authors were not decoded correctly: %+v", info.Authors)
+	}
+	if !reflect.DeepEqual(info.Types, []string{"dav", "prevent_group_restriction"}) {
+		t.Errorf("Types were not decoded correctly: %v", info.Types)
+	}
+	if info.Dependencies.Nextcloud != (VersionRange{Min: "27", Max: "27"}) || info.Dependencies.PHP.Min != "8.0" {
+		t.Errorf("Dependencies were not decoded correctly: %+v", info.Dependencies)
+	}
+
+	info, err = api.GetAppInfo("files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(info.Authors, []Author{{Name: "Robin Appelman"}}) || !reflect.DeepEqual(info.Types, []string{"filesystem"}) || !reflect.DeepEqual(info.Category, []string{"files"}) {
+		t.Errorf("Single values were not decoded correctly: %+v", info)
+	}
+
+	if _, err := api.GetAppInfo("unknown"); !errors.Is(err, ErrAppDoesNotExist) {
+		t.Errorf("Expected ErrAppDoesNotExist, got %v", err)
+	}
+
+	versions, err := api.GetAppVersions("all")
+	if err != nil || !reflect.DeepEqual(versions, map[string]string{"files": "1.22.0", "spreed": "17.1.3"}) {
+		t.Errorf("Unexpected versions %v (%v)", versions, err)
+	}
+
+	if ok, err := api.IsAppVersionAtLeast("spreed", "17.1"); err != nil || !ok {
+		t.Error("Talk 17.1.3 should be at least 17.1")
+	}
+	if ok, err := api.IsAppVersionAtLeast("spreed", "18.0.0"); err != nil || ok {
+		t.Error("Talk 17.1.3 should not be at least 18.0.0")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.15.0", "1.2", 1},
+		{"1.2", "1.2.0", 0},
+		{"27.1.0.4", "27.1.1", -1},
+		{"v2.0.0-beta.1", "2.0.0", 0},
+		{"9", "10", -1},
+	} {
+		if result := CompareVersions(tc.a, tc.b); result != tc.expected {
+			t.Errorf("CompareVersions(%q, %q) = %d, expected %d", tc.a, tc.b, result, tc.expected)
+		}
+	}
+}
+
+func TestEnableAppForGroups(t *testing.T) {
+	var body map[string][]string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/index.php/settings/apps/enable" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		if body["appIds"][0] == "unknown" {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintln(w, `{"data":{"message":"App not found"}}`)
+			return
+		}
+		fmt.Fprintln(w, `{"data":{"update_required":false}}`)
+	}))
+	defer ts.Close()
+
+	nc := nextcloudgo.NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"}
+	api := New(nc)
+
+	if err := api.EnableAppForGroups("spreed", []string{"sales", "support"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(body, map[string][]string{"appIds": {"spreed"}, "groups": {"sales", "support"}}) {
+		t.Errorf("Unexpected request body %v", body)
+	}
+
+	err := api.EnableAppForGroups("unknown", []string{"sales"})
+	var ocsErr *ocs.Error
+	if !errors.As(err, &ocsErr) || ocsErr.Message != "App not found" {
+		t.Errorf("Expected the message of the server, got %v", err)
+	}
+}
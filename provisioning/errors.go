@@ -0,0 +1,149 @@
+package provisioning
+
+import (
+	"errors"
+
+	"github.com/nextcloud/nextcloudgo/ocs"
+)
+
+var (
+	// ErrUserDoesNotExist when the user does not exist
+	ErrUserDoesNotExist = errors.New("User does not exist")
+	// ErrUserAlreadyExists when the user already exists
+	ErrUserAlreadyExists = errors.New("User already exists")
+	// ErrGroupDoesNotExist when the group does not exist
+	ErrGroupDoesNotExist = errors.New("Group does not exist")
+	// ErrGroupAlreadyExists when the group already exists
+	ErrGroupAlreadyExists = errors.New("Group already exists")
+	// ErrAppDoesNotExist when the app does not exist
+	ErrAppDoesNotExist = errors.New("App does not exist")
+	// ErrUserNotSubadmin when the user is not a subadmin of the group
+	ErrUserNotSubadmin = errors.New("User is not a subadmin of the group")
+
+	// ErrInvalidInput when the server rejected the given values
+	ErrInvalidInput = errors.New("Invalid input data")
+	// ErrInvalidFilter when an unknown app filter was given
+	ErrInvalidFilter = errors.New("Invalid filter given")
+	// ErrInvalidPassword when the password does not match the password policy,
+	// the hint of the server is available as message of the *ocs.Error
+	ErrInvalidPassword = errors.New("Password does not match the password policy")
+	// ErrMissingPasswordAndEmail when a user is created without password and email
+	ErrMissingPasswordAndEmail = errors.New("Password and email are both empty")
+	// ErrNoGroupSpecified when a subadmin creates a user without group or no group was given
+	ErrNoGroupSpecified = errors.New("No group specified")
+	// ErrInvitationFailed when the user was created, but the welcome email could not be sent
+	ErrInvitationFailed = errors.New("Invitation email could not be sent")
+
+	// ErrUnauthorized when the login was not accepted by the server
+	ErrUnauthorized = errors.New("Unauthorized")
+	// ErrInsufficientPermissions when the logged in user is not allowed to perform the action
+	ErrInsufficientPermissions = errors.New("Insufficient permissions")
+
+	// ErrDeleteAdminGroup when trying to delete the admin group
+	ErrDeleteAdminGroup = errors.New("Admin group can not be deleted")
+	// ErrDeleteCurrentUser when trying to delete the logged in user
+	ErrDeleteCurrentUser = errors.New("The current user can not be deleted")
+)
+
+// Error is returned when the server rejected a request.
+// It wraps the matching sentinel error (if any) and the *ocs.Error of the response,
+// so both errors.Is(err, ErrUserDoesNotExist) and errors.As(err, &ocsErr) work.
+type Error struct {
+	// Op describes the failed operation, e.g. "creating the user"
+	Op string
+	// Err is the sentinel error of the status code, nil for unknown failures
+	Err error
+	// OCS is the error of the response
+	OCS *ocs.Error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return "An error occured while " + e.Op
+}
+
+// Unwrap returns the sentinel and the OCS error
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.OCS}
+	}
+	return []error{e.Err, e.OCS}
+}
+
+// statusErrors maps the OCS status codes of an endpoint to sentinel errors.
+// OCS v2 reports 997 and 998 as 401 and 404, so both variants are listed.
+type statusErrors map[int]error
+
+var commonErrors = statusErrors{
+	401: ErrUnauthorized,
+	997: ErrUnauthorized,
+	403: ErrInsufficientPermissions,
+}
+
+var (
+	getAppsErrors     = statusErrors{101: ErrInvalidFilter}
+	appErrors         = statusErrors{404: ErrAppDoesNotExist, 998: ErrAppDoesNotExist}
+	createGroupErrors = statusErrors{101: ErrInvalidInput, 102: ErrGroupAlreadyExists}
+	deleteGroupErrors = statusErrors{101: ErrGroupDoesNotExist}
+	groupErrors       = statusErrors{404: ErrGroupDoesNotExist, 998: ErrGroupDoesNotExist}
+	editGroupErrors   = statusErrors{101: ErrInvalidInput, 102: ErrInvalidInput, 404: ErrGroupDoesNotExist, 998: ErrGroupDoesNotExist}
+	userErrors        = statusErrors{404: ErrUserDoesNotExist, 998: ErrUserDoesNotExist}
+	changeUserErrors  = statusErrors{101: ErrUserDoesNotExist}
+	editUserErrors    = statusErrors{
+		101: ErrUserDoesNotExist,
+		102: ErrInvalidInput,
+		103: ErrInvalidInput,
+		107: ErrInvalidPassword,
+	}
+	createUserErrors = statusErrors{
+		101: ErrInvalidInput,
+		102: ErrUserAlreadyExists,
+		104: ErrGroupDoesNotExist,
+		105: ErrInsufficientPermissions,
+		106: ErrNoGroupSpecified,
+		107: ErrInvalidPassword,
+		108: ErrMissingPasswordAndEmail,
+		109: ErrInvitationFailed,
+	}
+	memberErrors = statusErrors{
+		101: ErrNoGroupSpecified,
+		102: ErrGroupDoesNotExist,
+		103: ErrUserDoesNotExist,
+		104: ErrInsufficientPermissions,
+	}
+	addSubadminErrors    = statusErrors{101: ErrUserDoesNotExist, 102: ErrGroupDoesNotExist}
+	removeSubadminErrors = statusErrors{101: ErrUserDoesNotExist, 102: ErrUserNotSubadmin}
+	userSubadminErrors   = statusErrors{101: ErrUserDoesNotExist, 404: ErrUserDoesNotExist, 998: ErrUserDoesNotExist}
+	groupSubadminErrors  = statusErrors{101: ErrGroupDoesNotExist, 404: ErrGroupDoesNotExist, 998: ErrGroupDoesNotExist}
+)
+
+// wrapError turns an *ocs.Error into an *Error for the given operation.
+// Transport and decoding errors are returned unchanged.
+func wrapError(op string, err error, codes statusErrors) error {
+	e := ocsError(err)
+	if e == nil {
+		return err
+	}
+
+	code := e.StatusCode
+	if code == 0 {
+		code = e.HTTPStatus
+	}
+
+	sentinel := codes[code]
+	if sentinel == nil {
+		sentinel = commonErrors[code]
+	}
+	return &Error{Op: op, Err: sentinel, OCS: e}
+}
+
+// ocsError returns the *ocs.Error in err, or nil for transport and decoding errors
+func ocsError(err error) *ocs.Error {
+	var e *ocs.Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return nil
+}
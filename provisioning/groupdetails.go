@@ -0,0 +1,124 @@
+package provisioning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/nextcloud/nextcloudgo"
+	"github.com/nextcloud/nextcloudgo/ocs"
+)
+
+// Group holds the details of a group
+type Group struct {
+	ID          string
+	DisplayName string
+	// UserCount is -1 when the group backend can not count its members
+	UserCount int
+	Disabled  bool
+	// CanAdd is true when the backend allows adding members
+	CanAdd bool
+	// CanRemove is true when the backend allows removing members
+	CanRemove bool
+}
+
+// UnmarshalJSON decodes the group as sent by the provisioning API,
+// which uses false as user count when counting is not supported and 0/1 for disabled
+func (g *Group) UnmarshalJSON(b []byte) error {
+	var data struct {
+		ID          string          `json:"id"`
+		DisplayName string          `json:"displayname"`
+		UserCount   json.RawMessage `json:"usercount"`
+		Disabled    json.RawMessage `json:"disabled"`
+		CanAdd      bool            `json:"canAdd"`
+		CanRemove   bool            `json:"canRemove"`
+	}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	*g = Group{
+		ID:          data.ID,
+		DisplayName: data.DisplayName,
+		UserCount:   -1,
+		CanAdd:      data.CanAdd,
+		CanRemove:   data.CanRemove,
+	}
+	if count, err := strconv.Atoi(string(data.UserCount)); err == nil {
+		g.UserCount = count
+	}
+	switch string(data.Disabled) {
+	case "1", "true", `"1"`:
+		g.Disabled = true
+	}
+	return nil
+}
+
+type groupDetailsList struct {
+	Groups []Group `json:"groups"`
+}
+
+// GetGroupDetails returns the details of the groups matching the given search
+// The search is with wildcards on both ends
+// Setting offset and limit to 0 will return all groups
+func (api *Provisioning) GetGroupDetails(search string, offset, limit int) ([]Group, error) {
+	return api.GetGroupDetailsContext(context.Background(), search, offset, limit)
+}
+
+// GetGroupDetailsContext is like GetGroupDetails but aborts the request when ctx is done
+func (api *Provisioning) GetGroupDetailsContext(ctx context.Context, search string, offset, limit int) ([]Group, error) {
+	query := url.Values{"format": {"json"}}
+	if search != "" {
+		query.Set("search", search)
+	}
+	if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	res, err := ocs.DoContext[groupDetailsList](ctx, &api.ocs, http.MethodGet, endpoint+"/groups/details?"+query.Encode(), nil, true)
+	if err != nil {
+		return []Group{}, wrapError("searching for groups", err, nil)
+	}
+
+	return res.Data.Groups, nil
+}
+
+// CreateGroupWithDisplayName creates the group with a readable name on the server if it does not exist yet
+// Returns ErrGroupAlreadyExists when the group already exists
+// Returns ErrInvalidInput when the group name is invalid
+func (api *Provisioning) CreateGroupWithDisplayName(groupid, displayName string) error {
+	return api.CreateGroupWithDisplayNameContext(context.Background(), groupid, displayName)
+}
+
+// CreateGroupWithDisplayNameContext is like CreateGroupWithDisplayName but aborts the request when ctx is done
+func (api *Provisioning) CreateGroupWithDisplayNameContext(ctx context.Context, groupid, displayName string) error {
+	return api.createGroup(ctx, map[string]string{"groupid": groupid, "displayname": displayName})
+}
+
+// UpdateGroupDisplayName changes the readable name of the group
+// Returns ErrGroupDoesNotExist when the group does not exist
+// Returns nextcloudgo.ErrUnsupportedByServer on servers older than Nextcloud 21
+func (api *Provisioning) UpdateGroupDisplayName(groupid, displayName string) error {
+	return api.UpdateGroupDisplayNameContext(context.Background(), groupid, displayName)
+}
+
+// UpdateGroupDisplayNameContext is like UpdateGroupDisplayName but aborts the request when ctx is done
+func (api *Provisioning) UpdateGroupDisplayNameContext(ctx context.Context, groupid, displayName string) error {
+	if err := api.nc.RequireContext(ctx, nextcloudgo.FeatureGroupDisplayName); err != nil {
+		return err
+	}
+
+	body := map[string]string{"key": "displayname", "value": displayName}
+	reader := new(bytes.Buffer)
+	json.NewEncoder(reader).Encode(body)
+
+	url := endpoint + "/groups/" + groupid + "?format=json"
+	_, err := ocs.DoContext[json.RawMessage](ctx, &api.ocs, http.MethodPut, url, reader, true)
+	return wrapError("updating the group", err, editGroupErrors)
+}
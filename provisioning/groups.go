@@ -2,73 +2,64 @@ package provisioning
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"net/http"
 
 	"github.com/nextcloud/nextcloudgo/ocs"
 )
 
-var (
-	// ErrGroupDoesNotExist when the group does not exist
-	ErrGroupDoesNotExist = errors.New("Group does not exist")
-	// ErrGroupAlreadyExists when the group already exists
-	ErrGroupAlreadyExists = errors.New("Group already exists")
-)
+type groupList struct {
+	Groups []string `json:"groups"`
+}
 
 // CreateGroup creates the group on the server if it does not exist yet
 // Returns ErrGroupAlreadyExists when the group already exists
+// Returns ErrInvalidInput when the group name is invalid
 func (api *Provisioning) CreateGroup(groupid string) error {
-	body := map[string]string{"groupid": groupid}
+	return api.CreateGroupContext(context.Background(), groupid)
+}
+
+// CreateGroupContext is like CreateGroup but aborts the request when ctx is done
+func (api *Provisioning) CreateGroupContext(ctx context.Context, groupid string) error {
+	return api.createGroup(ctx, map[string]string{"groupid": groupid})
+}
+
+func (api *Provisioning) createGroup(ctx context.Context, body map[string]string) error {
 	reader := new(bytes.Buffer)
 	json.NewEncoder(reader).Encode(body)
 
 	url := endpoint + "/groups?format=json"
-	content, status, err := api.ocs.RequestWithBody(http.MethodPost, url, reader, true)
-	if err != nil {
-		return err
-	}
-
-	if status != http.StatusOK {
-		if ocs.ValidateStatusCode(content, 101) {
-			return errors.New("Provided group name is invalid")
-		}
-		if ocs.ValidateStatusCode(content, 102) {
-			return ErrGroupAlreadyExists
-		}
-		return errors.New("An error occured while creating the group")
-	}
-
-	return nil
+	_, err := ocs.DoContext[json.RawMessage](ctx, &api.ocs, http.MethodPost, url, reader, true)
+	return wrapError("creating the group", err, createGroupErrors)
 }
 
 // DeleteGroup deletes the given group from the server
 // The group admin can not be deleted
 // Returns ErrGroupDoesNotExist when the group does not exist
 func (api *Provisioning) DeleteGroup(groupid string) error {
+	return api.DeleteGroupContext(context.Background(), groupid)
+}
+
+// DeleteGroupContext is like DeleteGroup but aborts the request when ctx is done
+func (api *Provisioning) DeleteGroupContext(ctx context.Context, groupid string) error {
 	if groupid == "admin" {
-		return errors.New("Admin group can not be deleted")
+		return ErrDeleteAdminGroup
 	}
 
 	url := endpoint + "/groups/" + groupid + "?format=json"
-	content, status, err := api.ocs.Request(http.MethodDelete, url, true)
-	if err != nil {
-		return err
-	}
-
-	if status != http.StatusOK {
-		if ocs.ValidateStatusCode(content, 101) {
-			return ErrGroupDoesNotExist
-		}
-		return errors.New("An error occured while deleting the group")
-	}
-
-	return nil
+	_, err := ocs.DoContext[json.RawMessage](ctx, &api.ocs, http.MethodDelete, url, nil, true)
+	return wrapError("deleting the group", err, deleteGroupErrors)
 }
 
 // GetGroups returns all groups from the server matching the given search
 // The search is with wildcards on both ends
 func (api *Provisioning) GetGroups(search string) ([]string, error) {
+	return api.GetGroupsContext(context.Background(), search)
+}
+
+// GetGroupsContext is like GetGroups but aborts the request when ctx is done
+func (api *Provisioning) GetGroupsContext(ctx context.Context, search string) ([]string, error) {
 	var url string
 	if search != "" {
 		url = endpoint + "/groups?format=json&search=" + search
@@ -76,40 +67,39 @@ func (api *Provisioning) GetGroups(search string) ([]string, error) {
 		url = endpoint + "/groups?format=json"
 	}
 
-	content, status, err := api.ocs.Request(http.MethodGet, url, true)
+	res, err := ocs.DoContext[groupList](ctx, &api.ocs, http.MethodGet, url, nil, true)
 	if err != nil {
-		return []string{}, err
-	}
-
-	if status != http.StatusOK {
-		return []string{}, errors.New("An error occured while searching for groups")
+		return []string{}, wrapError("searching for groups", err, nil)
 	}
 
-	return ocs.GetStringList(content, []string{"ocs", "data", "groups"})
+	return res.Data.Groups, nil
 }
 
 // GetGroupMembers returns all users that are members of the given group
 // Returns ErrGroupDoesNotExist when the group does not exist
 func (api *Provisioning) GetGroupMembers(groupid string) ([]string, error) {
+	return api.GetGroupMembersContext(context.Background(), groupid)
+}
+
+// GetGroupMembersContext is like GetGroupMembers but aborts the request when ctx is done
+func (api *Provisioning) GetGroupMembersContext(ctx context.Context, groupid string) ([]string, error) {
 	url := endpoint + "/groups/" + groupid + "?format=json"
-	content, status, err := api.ocs.Request(http.MethodGet, url, true)
+	res, err := ocs.DoContext[userList](ctx, &api.ocs, http.MethodGet, url, nil, true)
 	if err != nil {
-		return []string{}, err
-	}
-
-	if status == http.StatusNotFound {
-		return []string{}, ErrGroupDoesNotExist
-	}
-	if status != http.StatusOK {
-		return []string{}, errors.New("An error occured while getting the members of the group")
+		return []string{}, wrapError("getting the members of the group", err, groupErrors)
 	}
 
-	return ocs.GetStringList(content, []string{"ocs", "data", "users"})
+	return res.Data.Users, nil
 }
 
 // GroupExists checks whether a group exists on the server
 func (api *Provisioning) GroupExists(groupid string) (bool, error) {
-	groups, err := api.GetGroups(groupid)
+	return api.GroupExistsContext(context.Background(), groupid)
+}
+
+// GroupExistsContext is like GroupExists but aborts the request when ctx is done
+func (api *Provisioning) GroupExistsContext(ctx context.Context, groupid string) (bool, error) {
+	groups, err := api.GetGroupsContext(ctx, groupid)
 	if err != nil {
 		return false, err
 	}
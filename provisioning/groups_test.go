@@ -0,0 +1,109 @@
+package provisioning
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/nextcloud/nextcloudgo"
+)
+
+func TestGetGroupDetails(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/ocs/v2.php/cloud/groups/details" || r.URL.Query().Get("limit") != "2" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, `{"ocs":{"meta":{"status":"ok","statuscode":200,"message":"OK"},"data":{"groups":[`+
+			`{"id":"admin","displayname":"Administrators","usercount":1,"disabled":0,"canAdd":true,"canRemove":true},`+
+			`{"id":"cn=sales","displayname":"Sales","usercount":false,"disabled":1,"canAdd":false,"canRemove":false}]}}}`)
+	}))
+	defer ts.Close()
+
+	nc := nextcloudgo.NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"}
+	api := New(nc)
+
+	groups, err := api.GetGroupDetails("", 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Group{
+		{ID: "admin", DisplayName: "Administrators", UserCount: 1, CanAdd: true, CanRemove: true},
+		{ID: "cn=sales", DisplayName: "Sales", UserCount: -1, Disabled: true},
+	}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("Unexpected groups %+v", groups)
+	}
+}
+
+func TestGroupDisplayName(t *testing.T) {
+	var requests []map[string]string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/status.php" {
+			fmt.Fprintln(w, `{"installed":true,"maintenance":false,"version":"28.0.4.1"}`)
+			return
+		}
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		body["method"] = r.Method
+		body["path"] = r.URL.Path
+		requests = append(requests, body)
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/ocs/v2.php/cloud/groups/unknown" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintln(w, `{"ocs":{"meta":{"status":"failure","statuscode":404,"message":"Group does not exist"},"data":[]}}`)
+			return
+		}
+		fmt.Fprintln(w, `{"ocs":{"meta":{"status":"ok","statuscode":200,"message":"OK"},"data":[]}}`)
+	}))
+	defer ts.Close()
+
+	nc := nextcloudgo.NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"}
+	api := New(nc)
+
+	if err := api.CreateGroupWithDisplayName("cn=sales", "Sales"); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.UpdateGroupDisplayName("cn=sales", "Sales & Marketing"); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.UpdateGroupDisplayName("unknown", "Unknown"); !errors.Is(err, ErrGroupDoesNotExist) {
+		t.Errorf("Expected ErrGroupDoesNotExist, got %v", err)
+	}
+
+	expected := []map[string]string{
+		{"method": http.MethodPost, "path": "/ocs/v2.php/cloud/groups", "groupid": "cn=sales", "displayname": "Sales"},
+		{"method": http.MethodPut, "path": "/ocs/v2.php/cloud/groups/cn=sales", "key": "displayname", "value": "Sales & Marketing"},
+		{"method": http.MethodPut, "path": "/ocs/v2.php/cloud/groups/unknown", "key": "displayname", "value": "Unknown"},
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("Unexpected requests %v", requests)
+	}
+}
+
+func TestGroupDisplayNameUnsupported(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintln(w, `{"ocs":{"meta":{"status":"ok","statuscode":200,"message":"OK"},"data":[]}}`)
+	}))
+	defer ts.Close()
+
+	nc, err := nextcloudgo.New(ts.URL, "admin", "admin", nextcloudgo.WithServerVersion("20.0.14"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := New(nc)
+	if err := api.UpdateGroupDisplayName("cn=sales", "Sales"); !errors.Is(err, nextcloudgo.ErrUnsupportedByServer) {
+		t.Errorf("Expected ErrUnsupportedByServer, got %v", err)
+	}
+	if requests != 0 {
+		t.Error("No request should be sent to a server without support")
+	}
+}
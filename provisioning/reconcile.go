@@ -0,0 +1,380 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultConcurrency is the number of parallel requests when applying a plan
+const DefaultConcurrency = 4
+
+// ErrDependencyFailed is reported for actions that were skipped,
+// because creating their user or group failed
+var ErrDependencyFailed = errors.New("Skipped because a required action failed")
+
+// DesiredUser is the state a user should have after reconciling.
+// Empty values are left unchanged on the server.
+type DesiredUser struct {
+	ID string
+	// Password is only used when the user has to be created
+	Password    string
+	DisplayName string
+	Email       string
+	// Quota accepts the formats of ParseQuota and "default" for the default quota of the instance.
+	// The default is always set again, because its limit is not known.
+	Quota string
+	// Enabled is left unchanged when nil
+	Enabled *bool
+	// Groups the user should be a member of
+	Groups []string
+}
+
+// DesiredGroup is a group that should exist after reconciling
+type DesiredGroup struct {
+	ID          string
+	DisplayName string
+}
+
+// DesiredState describes users, groups and memberships that should exist on the server.
+// Users and groups that are not listed are never touched.
+type DesiredState struct {
+	Users []DesiredUser
+	// Groups are the managed groups. Members of these groups are removed,
+	// when they are listed in Users but the group is not in their Groups.
+	// Groups only referenced by users are created, but never pruned.
+	Groups []DesiredGroup
+}
+
+// ActionType is the kind of change an Action performs
+type ActionType string
+
+// Actions performed by the reconciler
+const (
+	ActionCreateGroup         ActionType = "create-group"
+	ActionSetGroupDisplayName ActionType = "set-group-displayname"
+	ActionCreateUser          ActionType = "create-user"
+	ActionSetDisplayName      ActionType = "set-displayname"
+	ActionSetEmail            ActionType = "set-email"
+	ActionSetQuota            ActionType = "set-quota"
+	ActionEnableUser          ActionType = "enable-user"
+	ActionDisableUser         ActionType = "disable-user"
+	ActionAddToGroup          ActionType = "add-to-group"
+	ActionRemoveFromGroup     ActionType = "remove-from-group"
+)
+
+// Action is a single change the reconciler performs
+type Action struct {
+	Type  ActionType
+	User  string
+	Group string
+	// Value is the new display name, email or quota
+	Value string
+
+	password string
+}
+
+func (a Action) String() string {
+	var s string
+	switch a.Type {
+	case ActionCreateGroup, ActionSetGroupDisplayName:
+		s = fmt.Sprintf("%s %s %s", a.Type, a.Group, a.Value)
+	case ActionAddToGroup, ActionRemoveFromGroup:
+		s = fmt.Sprintf("%s %s %s", a.Type, a.User, a.Group)
+	default:
+		s = fmt.Sprintf("%s %s %s", a.Type, a.User, a.Value)
+	}
+	return strings.TrimSpace(s)
+}
+
+// phase returns the order in which actions are applied,
+// groups and users have to exist before they are changed
+func (a Action) phase() int {
+	switch a.Type {
+	case ActionCreateGroup:
+		return 0
+	case ActionCreateUser:
+		return 1
+	}
+	return 2
+}
+
+// Plan is the list of actions needed to reach the desired state
+type Plan struct {
+	Actions []Action
+}
+
+// Result is the outcome of a single action
+type Result struct {
+	Action Action
+	// Err is nil when the action succeeded or was not applied in a dry run
+	Err error
+}
+
+// Report lists the outcome of every action of the plan
+type Report struct {
+	DryRun  bool
+	Results []Result
+}
+
+// Failed returns the results of all actions that failed or were skipped
+func (r Report) Failed() []Result {
+	var failed []Result
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// ReconcileOptions configure how a plan is applied
+type ReconcileOptions struct {
+	// DryRun only computes the plan, no changes are made
+	DryRun bool
+	// Concurrency limits the number of parallel requests, DefaultConcurrency when 0
+	Concurrency int
+}
+
+// Reconcile compares the desired state with the server and applies the differences
+func (api *Provisioning) Reconcile(state DesiredState, options ReconcileOptions) (Report, error) {
+	return api.ReconcileContext(context.Background(), state, options)
+}
+
+// ReconcileContext is like Reconcile but aborts the requests when ctx is done
+func (api *Provisioning) ReconcileContext(ctx context.Context, state DesiredState, options ReconcileOptions) (Report, error) {
+	plan, err := api.PlanReconcileContext(ctx, state)
+	if err != nil {
+		return Report{}, err
+	}
+
+	if options.DryRun {
+		report := Report{DryRun: true}
+		for _, action := range plan.Actions {
+			report.Results = append(report.Results, Result{Action: action})
+		}
+		return report, nil
+	}
+
+	return api.ApplyPlanContext(ctx, plan, options.Concurrency), nil
+}
+
+// PlanReconcile compares the desired state with the server and returns the actions needed to reach it
+func (api *Provisioning) PlanReconcile(state DesiredState) (Plan, error) {
+	return api.PlanReconcileContext(context.Background(), state)
+}
+
+// PlanReconcileContext is like PlanReconcile but aborts the requests when ctx is done
+func (api *Provisioning) PlanReconcileContext(ctx context.Context, state DesiredState) (Plan, error) {
+	groups, err := api.GetGroupDetailsContext(ctx, "", 0, 0)
+	if err != nil {
+		return Plan{}, err
+	}
+	existingGroups := map[string]Group{}
+	for _, group := range groups {
+		existingGroups[group.ID] = group
+	}
+
+	existingUsers := map[string]User{}
+	it := api.IterateUsersContext(ctx, "", 0)
+	for it.Next() {
+		existingUsers[it.User().ID] = it.User()
+	}
+	if err := it.Err(); err != nil {
+		return Plan{}, err
+	}
+
+	return diffState(state, existingGroups, existingUsers)
+}
+
+func diffState(state DesiredState, existingGroups map[string]Group, existingUsers map[string]User) (Plan, error) {
+	plan := Plan{}
+
+	managed := map[string]bool{}
+	for _, group := range sortedGroups(state.Groups) {
+		managed[group.ID] = true
+		existing, ok := existingGroups[group.ID]
+		if !ok {
+			plan.Actions = append(plan.Actions, Action{Type: ActionCreateGroup, Group: group.ID, Value: group.DisplayName})
+			existingGroups[group.ID] = Group{ID: group.ID, DisplayName: group.DisplayName}
+			continue
+		}
+		if group.DisplayName != "" && group.DisplayName != existing.DisplayName {
+			plan.Actions = append(plan.Actions, Action{Type: ActionSetGroupDisplayName, Group: group.ID, Value: group.DisplayName})
+		}
+	}
+
+	for _, user := range sortedUsers(state.Users) {
+		existing, ok := existingUsers[user.ID]
+		if !ok {
+			plan.Actions = append(plan.Actions, Action{Type: ActionCreateUser, User: user.ID, password: user.Password})
+			existing = User{ID: user.ID, Enabled: true, Quota: Quota{Limit: QuotaUnlimited}}
+		}
+
+		if user.DisplayName != "" && user.DisplayName != existing.DisplayName {
+			plan.Actions = append(plan.Actions, Action{Type: ActionSetDisplayName, User: user.ID, Value: user.DisplayName})
+		}
+		if user.Email != "" && user.Email != existing.Email {
+			plan.Actions = append(plan.Actions, Action{Type: ActionSetEmail, User: user.ID, Value: user.Email})
+		}
+		if strings.EqualFold(strings.TrimSpace(user.Quota), "default") {
+			plan.Actions = append(plan.Actions, Action{Type: ActionSetQuota, User: user.ID, Value: "default"})
+		} else if user.Quota != "" {
+			limit, err := ParseQuota(user.Quota)
+			if err != nil {
+				return Plan{}, fmt.Errorf("User %s: %w", user.ID, err)
+			}
+			// New users get the default quota, which is unknown here
+			if !ok || limit != existing.Quota.Limit {
+				plan.Actions = append(plan.Actions, Action{Type: ActionSetQuota, User: user.ID, Value: user.Quota})
+			}
+		}
+		if user.Enabled != nil && *user.Enabled != existing.Enabled {
+			if *user.Enabled {
+				plan.Actions = append(plan.Actions, Action{Type: ActionEnableUser, User: user.ID})
+			} else {
+				plan.Actions = append(plan.Actions, Action{Type: ActionDisableUser, User: user.ID})
+			}
+		}
+
+		desired := map[string]bool{}
+		for _, group := range user.Groups {
+			desired[group] = true
+		}
+		member := map[string]bool{}
+		for _, group := range existing.Groups {
+			member[group] = true
+		}
+
+		for _, group := range sortedKeys(desired) {
+			if member[group] {
+				continue
+			}
+			if _, ok := existingGroups[group]; !ok {
+				plan.Actions = append(plan.Actions, Action{Type: ActionCreateGroup, Group: group})
+				existingGroups[group] = Group{ID: group}
+			}
+			plan.Actions = append(plan.Actions, Action{Type: ActionAddToGroup, User: user.ID, Group: group})
+		}
+		for _, group := range sortedKeys(member) {
+			if managed[group] && !desired[group] {
+				plan.Actions = append(plan.Actions, Action{Type: ActionRemoveFromGroup, User: user.ID, Group: group})
+			}
+		}
+	}
+
+	sort.SliceStable(plan.Actions, func(i, j int) bool {
+		return plan.Actions[i].phase() < plan.Actions[j].phase()
+	})
+	return plan, nil
+}
+
+// ApplyPlan performs the actions of the plan with up to concurrency parallel requests.
+// Groups are created first, then users, then all other changes. Actions of users and
+// groups that could not be created are reported with ErrDependencyFailed.
+func (api *Provisioning) ApplyPlan(plan Plan, concurrency int) Report {
+	return api.ApplyPlanContext(context.Background(), plan, concurrency)
+}
+
+// ApplyPlanContext is like ApplyPlan but aborts the requests when ctx is done
+func (api *Provisioning) ApplyPlanContext(ctx context.Context, plan Plan, concurrency int) Report {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	report := Report{Results: make([]Result, len(plan.Actions))}
+	failedUsers := map[string]bool{}
+	failedGroups := map[string]bool{}
+
+	for phase := 0; phase <= 2; phase++ {
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, concurrency)
+
+		for i, action := range plan.Actions {
+			if action.phase() != phase {
+				continue
+			}
+			report.Results[i].Action = action
+			if failedUsers[action.User] || failedGroups[action.Group] {
+				report.Results[i].Err = ErrDependencyFailed
+				continue
+			}
+
+			wg.Add(1)
+			slots <- struct{}{}
+			go func(i int, action Action) {
+				defer wg.Done()
+				report.Results[i].Err = api.applyAction(ctx, action)
+				<-slots
+			}(i, action)
+		}
+		wg.Wait()
+
+		for _, result := range report.Results {
+			if result.Err == nil {
+				continue
+			}
+			switch result.Action.Type {
+			case ActionCreateGroup:
+				failedGroups[result.Action.Group] = true
+			case ActionCreateUser:
+				failedUsers[result.Action.User] = true
+			}
+		}
+	}
+
+	return report
+}
+
+func (api *Provisioning) applyAction(ctx context.Context, action Action) error {
+	switch action.Type {
+	case ActionCreateGroup:
+		if action.Value != "" {
+			return api.CreateGroupWithDisplayNameContext(ctx, action.Group, action.Value)
+		}
+		return api.CreateGroupContext(ctx, action.Group)
+	case ActionSetGroupDisplayName:
+		return api.UpdateGroupDisplayNameContext(ctx, action.Group, action.Value)
+	case ActionCreateUser:
+		return api.CreateUserContext(ctx, action.User, action.password)
+	case ActionSetDisplayName:
+		return api.SetUserDisplayNameContext(ctx, action.User, action.Value)
+	case ActionSetEmail:
+		return api.SetUserEmailContext(ctx, action.User, action.Value)
+	case ActionSetQuota:
+		return api.SetUserQuotaContext(ctx, action.User, action.Value)
+	case ActionEnableUser:
+		return api.EnableUserContext(ctx, action.User)
+	case ActionDisableUser:
+		return api.DisableUserContext(ctx, action.User)
+	case ActionAddToGroup:
+		return api.AddUserToGroupContext(ctx, action.User, action.Group)
+	case ActionRemoveFromGroup:
+		return api.RemoveUserFromGroupContext(ctx, action.User, action.Group)
+	}
+	return fmt.Errorf("Unknown action %s", action.Type)
+}
+
+func sortedUsers(users []DesiredUser) []DesiredUser {
+	sorted := append([]DesiredUser{}, users...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	return sorted
+}
+
+func sortedGroups(groups []DesiredGroup) []DesiredGroup {
+	sorted := append([]DesiredGroup{}, groups...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	return sorted
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,206 @@
+package provisioning
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nextcloud/nextcloudgo"
+)
+
+// fakeProvisioning is a minimal in-memory provisioning API
+type fakeProvisioning struct {
+	mu     sync.Mutex
+	groups map[string]string
+	users  map[string]map[string]interface{}
+}
+
+func (f *fakeProvisioning) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var body map[string]string
+	json.NewDecoder(r.Body).Decode(&body)
+	w.Header().Set("Content-Type", "application/json")
+	respond := func(code int, data interface{}) {
+		if code != 200 {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		b, _ := json.Marshal(data)
+		fmt.Fprintf(w, `{"ocs":{"meta":{"status":"ok","statuscode":%d,"message":""},"data":%s}}`, code, b)
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/ocs/v2.php/cloud/"), "/")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/ocs/v2.php/cloud/groups/details":
+		groups := []map[string]interface{}{}
+		for id, name := range f.groups {
+			groups = append(groups, map[string]interface{}{"id": id, "displayname": name})
+		}
+		respond(200, map[string]interface{}{"groups": groups})
+	case r.Method == http.MethodGet && r.URL.Path == "/ocs/v2.php/cloud/users/details":
+		if r.URL.Query().Get("offset") != "" {
+			respond(200, map[string]interface{}{"users": []string{}})
+			return
+		}
+		respond(200, map[string]interface{}{"users": f.users})
+	case r.Method == http.MethodPost && r.URL.Path == "/ocs/v2.php/cloud/groups":
+		f.groups[body["groupid"]] = body["displayname"]
+		respond(200, []string{})
+	case r.Method == http.MethodPost && r.URL.Path == "/ocs/v2.php/cloud/users":
+		if body["password"] == "" {
+			respond(108, []string{})
+			return
+		}
+		f.users[body["userid"]] = map[string]interface{}{"id": body["userid"], "enabled": true, "groups": []string{}}
+		respond(200, []string{})
+	case len(parts) == 2 && r.Method == http.MethodPut:
+		f.users[parts[1]][body["key"]] = body["value"]
+		respond(200, []string{})
+	case len(parts) == 3 && r.Method == http.MethodPut:
+		f.users[parts[1]]["enabled"] = parts[2] == "enable"
+		respond(200, []string{})
+	case len(parts) == 3 && parts[2] == "groups":
+		user := f.users[parts[1]]
+		groups := user["groups"].([]string)
+		if r.Method == http.MethodPost {
+			user["groups"] = append(groups, body["groupid"])
+		} else {
+			remaining := []string{}
+			for _, group := range groups {
+				if group != body["groupid"] {
+					remaining = append(remaining, group)
+				}
+			}
+			user["groups"] = remaining
+		}
+		respond(200, []string{})
+	default:
+		respond(998, []string{})
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	fake := &fakeProvisioning{
+		groups: map[string]string{"admin": "admin", "sales": "Sales", "legacy": "legacy"},
+		users: map[string]map[string]interface{}{
+			"admin": {"id": "admin", "enabled": true, "groups": []string{"admin"}},
+			"alice": {"id": "alice", "enabled": true, "displayname": "Alice", "groups": []string{"sales", "legacy"}, "quota": map[string]interface{}{"quota": 5 << 30}},
+			"bob":   {"id": "bob", "enabled": true, "groups": []string{"sales"}},
+		},
+	}
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+
+	nc := nextcloudgo.NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"}
+	api := New(nc)
+
+	disabled := false
+	state := DesiredState{
+		Groups: []DesiredGroup{{ID: "sales", DisplayName: "Sales"}, {ID: "legacy"}, {ID: "support", DisplayName: "Support"}},
+		Users: []DesiredUser{
+			{ID: "alice", DisplayName: "Alice", Quota: "5 GB", Groups: []string{"sales", "support"}},
+			{ID: "bob", Enabled: &disabled, Groups: []string{"sales"}},
+			{ID: "carol", Password: "secret", Email: "carol@example.com", Groups: []string{"support", "interns"}},
+			{ID: "dave", Groups: []string{"sales"}},
+		},
+	}
+
+	report, err := api.Reconcile(state, ReconcileOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var planned []string
+	for _, result := range report.Results {
+		planned = append(planned, result.Action.String())
+	}
+	expected := []string{
+		"create-group support Support",
+		"create-group interns",
+		"create-user carol",
+		"create-user dave",
+		"add-to-group alice support",
+		"remove-from-group alice legacy",
+		"disable-user bob",
+		"set-email carol carol@example.com",
+		"add-to-group carol interns",
+		"add-to-group carol support",
+		"add-to-group dave sales",
+	}
+	if !reflect.DeepEqual(planned, expected) {
+		t.Errorf("Unexpected plan:\n%s", strings.Join(planned, "\n"))
+	}
+	if len(fake.groups) != 3 {
+		t.Error("Dry run should not change the server")
+	}
+
+	report, err = api.Reconcile(state, ReconcileOptions{Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := report.Failed()
+	if len(failed) != 2 {
+		t.Fatalf("Expected creating dave and adding him to sales to fail, got %v", failed)
+	}
+	if !errors.Is(failed[0].Err, ErrMissingPasswordAndEmail) || !errors.Is(failed[1].Err, ErrDependencyFailed) {
+		t.Errorf("Unexpected errors %v", failed)
+	}
+
+	plan, err := api.PlanReconcile(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Actions) != 2 || plan.Actions[0].Type != ActionCreateUser || plan.Actions[0].User != "dave" {
+		t.Errorf("Only dave should be left after applying, got %v", plan.Actions)
+	}
+}
+
+func TestReconcileDefaultQuota(t *testing.T) {
+	fake := &fakeProvisioning{
+		groups: map[string]string{},
+		users: map[string]map[string]interface{}{
+			"alice": {"id": "alice", "enabled": true, "groups": []string{}, "quota": map[string]interface{}{"quota": 5 << 30}},
+		},
+	}
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+	api := New(nextcloudgo.NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"})
+
+	plan, err := api.PlanReconcile(DesiredState{Users: []DesiredUser{{ID: "alice", Quota: " Default "}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Action{{Type: ActionSetQuota, User: "alice", Value: "default"}}
+	if !reflect.DeepEqual(plan.Actions, expected) {
+		t.Errorf("Unexpected plan %v", plan.Actions)
+	}
+}
+
+func TestParseQuota(t *testing.T) {
+	for quota, expected := range map[string]int64{
+		"1024":   1024,
+		"5 GB":   5 << 30,
+		"1.5MB":  3 << 19,
+		"10 kb":  10 << 10,
+		"none":   QuotaUnlimited,
+		"2 TB":   2 << 40,
+		" 7 b  ": 7,
+	} {
+		bytes, err := ParseQuota(quota)
+		if err != nil || bytes != expected {
+			t.Errorf("%q: expected %d, got %d (%v)", quota, expected, bytes, err)
+		}
+	}
+
+	for _, quota := range []string{"default", "5 XB", "-1 GB", ""} {
+		if _, err := ParseQuota(quota); err == nil {
+			t.Errorf("%q should be rejected", quota)
+		}
+	}
+}
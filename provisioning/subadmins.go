@@ -0,0 +1,84 @@
+package provisioning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/nextcloud/nextcloudgo/ocs"
+)
+
+// AddSubadmin makes the user a subadmin of the given group
+// Returns ErrUserDoesNotExist when the user does not exist
+// Returns ErrGroupDoesNotExist when the group does not exist
+func (api *Provisioning) AddSubadmin(userid, groupid string) error {
+	return api.AddSubadminContext(context.Background(), userid, groupid)
+}
+
+// AddSubadminContext is like AddSubadmin but aborts the request when ctx is done
+func (api *Provisioning) AddSubadminContext(ctx context.Context, userid, groupid string) error {
+	return api.changeSubadminState(ctx, userid, groupid, http.MethodPost)
+}
+
+// RemoveSubadmin removes the subadmin rights of the user for the given group
+// Returns ErrUserDoesNotExist when the user does not exist
+// Returns ErrUserNotSubadmin when the user is not a subadmin of the group
+func (api *Provisioning) RemoveSubadmin(userid, groupid string) error {
+	return api.RemoveSubadminContext(context.Background(), userid, groupid)
+}
+
+// RemoveSubadminContext is like RemoveSubadmin but aborts the request when ctx is done
+func (api *Provisioning) RemoveSubadminContext(ctx context.Context, userid, groupid string) error {
+	return api.changeSubadminState(ctx, userid, groupid, http.MethodDelete)
+}
+
+func (api *Provisioning) changeSubadminState(ctx context.Context, userid, groupid, method string) error {
+	body := map[string]string{"groupid": groupid}
+	reader := new(bytes.Buffer)
+	json.NewEncoder(reader).Encode(body)
+
+	url := endpoint + "/users/" + userid + "/subadmins?format=json"
+	_, err := ocs.DoContext[json.RawMessage](ctx, &api.ocs, method, url, reader, true)
+	if method == http.MethodPost {
+		return wrapError("adding the subadmin", err, addSubadminErrors)
+	}
+
+	return wrapError("removing the subadmin", err, removeSubadminErrors)
+}
+
+// GetUserSubadminGroups returns all groups the user is a subadmin of
+// Returns ErrUserDoesNotExist when the user does not exist
+func (api *Provisioning) GetUserSubadminGroups(userid string) ([]string, error) {
+	return api.GetUserSubadminGroupsContext(context.Background(), userid)
+}
+
+// GetUserSubadminGroupsContext is like GetUserSubadminGroups but aborts the request when ctx is done
+func (api *Provisioning) GetUserSubadminGroupsContext(ctx context.Context, userid string) ([]string, error) {
+	url := endpoint + "/users/" + userid + "/subadmins?format=json"
+
+	res, err := ocs.DoContext[[]string](ctx, &api.ocs, http.MethodGet, url, nil, true)
+	if err != nil {
+		return []string{}, wrapError("getting the subadmin groups of the user", err, userSubadminErrors)
+	}
+
+	return res.Data, nil
+}
+
+// GetGroupSubadmins returns all users that are subadmins of the given group
+// Returns ErrGroupDoesNotExist when the group does not exist
+func (api *Provisioning) GetGroupSubadmins(groupid string) ([]string, error) {
+	return api.GetGroupSubadminsContext(context.Background(), groupid)
+}
+
+// GetGroupSubadminsContext is like GetGroupSubadmins but aborts the request when ctx is done
+func (api *Provisioning) GetGroupSubadminsContext(ctx context.Context, groupid string) ([]string, error) {
+	url := endpoint + "/groups/" + groupid + "/subadmins?format=json"
+
+	res, err := ocs.DoContext[[]string](ctx, &api.ocs, http.MethodGet, url, nil, true)
+	if err != nil {
+		return []string{}, wrapError("getting the subadmins of the group", err, groupSubadminErrors)
+	}
+
+	return res.Data, nil
+}
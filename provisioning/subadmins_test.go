@@ -0,0 +1,92 @@
+package provisioning
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/nextcloud/nextcloudgo"
+)
+
+func TestSubadmins(t *testing.T) {
+	subadmins := map[string][]string{"alice": {"sales"}}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		failure := func(code int) {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"ocs":{"meta":{"status":"failure","statuscode":%d,"message":""},"data":[]}}`, code)
+		}
+		success := func(data interface{}) {
+			b, _ := json.Marshal(data)
+			fmt.Fprintf(w, `{"ocs":{"meta":{"status":"ok","statuscode":200,"message":"OK"},"data":%s}}`, b)
+		}
+
+		switch r.URL.Path {
+		case "/ocs/v2.php/cloud/users/alice/subadmins":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			switch r.Method {
+			case http.MethodGet:
+				success(subadmins["alice"])
+			case http.MethodPost:
+				if body["groupid"] == "unknown" {
+					failure(102)
+					return
+				}
+				subadmins["alice"] = append(subadmins["alice"], body["groupid"])
+				success([]string{})
+			case http.MethodDelete:
+				if body["groupid"] != "sales" {
+					failure(102)
+					return
+				}
+				success([]string{})
+			}
+		case "/ocs/v2.php/cloud/groups/sales/subadmins":
+			success([]string{"alice"})
+		case "/ocs/v2.php/cloud/groups/unknown/subadmins":
+			failure(101)
+		default:
+			failure(101)
+		}
+	}))
+	defer ts.Close()
+
+	nc := nextcloudgo.NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"}
+	api := New(nc)
+
+	if err := api.AddSubadmin("alice", "support"); err != nil {
+		t.Fatal(err)
+	}
+	groups, err := api.GetUserSubadminGroups("alice")
+	if err != nil || !reflect.DeepEqual(groups, []string{"sales", "support"}) {
+		t.Errorf("Unexpected subadmin groups %v (%v)", groups, err)
+	}
+	users, err := api.GetGroupSubadmins("sales")
+	if err != nil || !reflect.DeepEqual(users, []string{"alice"}) {
+		t.Errorf("Unexpected subadmins %v (%v)", users, err)
+	}
+
+	if err := api.RemoveSubadmin("alice", "sales"); err != nil {
+		t.Error(err)
+	}
+
+	for _, tc := range []struct {
+		err      error
+		expected error
+	}{
+		{api.AddSubadmin("alice", "unknown"), ErrGroupDoesNotExist},
+		{api.AddSubadmin("bob", "sales"), ErrUserDoesNotExist},
+		{api.RemoveSubadmin("alice", "support-2"), ErrUserNotSubadmin},
+		{func() error { _, err := api.GetUserSubadminGroups("bob"); return err }(), ErrUserDoesNotExist},
+		{func() error { _, err := api.GetGroupSubadmins("unknown"); return err }(), ErrGroupDoesNotExist},
+	} {
+		if !errors.Is(tc.err, tc.expected) {
+			t.Errorf("Expected %v, got %v", tc.expected, tc.err)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package provisioning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/nextcloud/nextcloudgo/ocs"
+)
+
+// UserField is the key of an editable user attribute
+type UserField string
+
+// Editable user attributes
+const (
+	FieldDisplayName UserField = "displayname"
+	FieldEmail       UserField = "email"
+	FieldQuota       UserField = "quota"
+	FieldPassword    UserField = "password"
+	FieldLanguage    UserField = "language"
+	FieldLocale      UserField = "locale"
+	FieldPhone       UserField = "phone"
+	FieldAddress     UserField = "address"
+	FieldWebsite     UserField = "website"
+	FieldTwitter     UserField = "twitter"
+)
+
+// QuotaUnlimited is the quota limit of users without a limit
+const QuotaUnlimited int64 = -3
+
+// Quota is the storage usage of a user in bytes
+type Quota struct {
+	Free  int64
+	Used  int64
+	Total int64
+	// Relative is the used share of the quota in percent
+	Relative float64
+	// Limit is the configured quota, QuotaUnlimited when there is none
+	Limit int64
+}
+
+// User holds the data of a user account
+type User struct {
+	ID          string
+	DisplayName string
+	Email       string
+	Quota       Quota
+	Groups      []string
+	Language    string
+	Locale      string
+	// LastLogin is the zero time when the user never logged in
+	LastLogin time.Time
+	Backend   string
+	Enabled   bool
+	Phone     string
+	Address   string
+	Website   string
+	Twitter   string
+}
+
+type userData struct {
+	ID          string   `json:"id"`
+	DisplayName string   `json:"displayname"`
+	Email       string   `json:"email"`
+	Groups      []string `json:"groups"`
+	Language    string   `json:"language"`
+	Locale      string   `json:"locale"`
+	LastLogin   int64    `json:"lastLogin"`
+	Backend     string   `json:"backend"`
+	Enabled     bool     `json:"enabled"`
+	Phone       string   `json:"phone"`
+	Address     string   `json:"address"`
+	Website     string   `json:"website"`
+	Twitter     string   `json:"twitter"`
+	// Quota is an empty array when the server can not read the storage of the user
+	Quota json.RawMessage `json:"quota"`
+}
+
+type quotaData struct {
+	// The server sends floats for large values
+	Free     float64 `json:"free"`
+	Used     float64 `json:"used"`
+	Total    float64 `json:"total"`
+	Relative float64 `json:"relative"`
+	// Limit is either the size in bytes or "none"
+	Limit json.RawMessage `json:"quota"`
+}
+
+// UnmarshalJSON decodes the user data as sent by the provisioning API
+func (u *User) UnmarshalJSON(b []byte) error {
+	var data userData
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	*u = User{
+		ID:          data.ID,
+		DisplayName: data.DisplayName,
+		Email:       data.Email,
+		Groups:      data.Groups,
+		Language:    data.Language,
+		Locale:      data.Locale,
+		Backend:     data.Backend,
+		Enabled:     data.Enabled,
+		Phone:       data.Phone,
+		Address:     data.Address,
+		Website:     data.Website,
+		Twitter:     data.Twitter,
+	}
+	if quota := bytes.TrimSpace(data.Quota); len(quota) == 0 || quota[0] != '[' {
+		var q quotaData
+		if len(quota) > 0 {
+			if err := json.Unmarshal(quota, &q); err != nil {
+				return err
+			}
+		}
+		u.Quota = Quota{Free: int64(q.Free), Used: int64(q.Used), Total: int64(q.Total), Relative: q.Relative, Limit: QuotaUnlimited}
+		var limit float64
+		if err := json.Unmarshal(q.Limit, &limit); err == nil && limit >= 0 {
+			u.Quota.Limit = int64(limit)
+		}
+	}
+	if data.LastLogin > 0 {
+		u.LastLogin = time.UnixMilli(data.LastLogin)
+	}
+	return nil
+}
+
+var quotaUnits = map[string]int64{
+	"":   1,
+	"b":  1,
+	"k":  1 << 10,
+	"kb": 1 << 10,
+	"m":  1 << 20,
+	"mb": 1 << 20,
+	"g":  1 << 30,
+	"gb": 1 << 30,
+	"t":  1 << 40,
+	"tb": 1 << 40,
+	"p":  1 << 50,
+	"pb": 1 << 50,
+}
+
+// ParseQuota converts a quota like "5 GB" or "1024" into bytes the same way the server does.
+// "none" returns QuotaUnlimited.
+func ParseQuota(quota string) (int64, error) {
+	quota = strings.ToLower(strings.TrimSpace(quota))
+	if quota == "none" {
+		return QuotaUnlimited, nil
+	}
+
+	number := strings.TrimRightFunc(quota, unicode.IsLetter)
+	unit, ok := quotaUnits[quota[len(number):]]
+	if !ok {
+		return 0, fmt.Errorf("Invalid quota %q", quota)
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("Invalid quota %q", quota)
+	}
+	return int64(math.Round(value * float64(unit))), nil
+}
+
+// GetUser returns the data of the given user
+// Returns ErrUserDoesNotExist when the user does not exist
+func (api *Provisioning) GetUser(userid string) (User, error) {
+	return api.GetUserContext(context.Background(), userid)
+}
+
+// GetUserContext is like GetUser but aborts the request when ctx is done
+func (api *Provisioning) GetUserContext(ctx context.Context, userid string) (User, error) {
+	url := endpoint + "/users/" + userid + "?format=json"
+
+	res, err := ocs.DoContext[User](ctx, &api.ocs, http.MethodGet, url, nil, true)
+	if err != nil {
+		return User{}, wrapError("getting the user", err, userErrors)
+	}
+
+	return res.Data, nil
+}
+
+// GetCurrentUser returns the data of the logged in user
+// This function can be used by all users
+func (api *Provisioning) GetCurrentUser() (User, error) {
+	return api.GetCurrentUserContext(context.Background())
+}
+
+// GetCurrentUserContext is like GetCurrentUser but aborts the request when ctx is done
+func (api *Provisioning) GetCurrentUserContext(ctx context.Context) (User, error) {
+	url := endpoint + "/user?format=json"
+
+	res, err := ocs.DoContext[User](ctx, &api.ocs, http.MethodGet, url, nil, true)
+	if err != nil {
+		return User{}, wrapError("getting the current user", err, nil)
+	}
+
+	return res.Data, nil
+}
+
+// SetUserData sets a single attribute of the user
+// Returns ErrUserDoesNotExist when the user does not exist
+// Returns ErrInvalidInput when the server rejected the value
+func (api *Provisioning) SetUserData(userid string, field UserField, value string) error {
+	return api.SetUserDataContext(context.Background(), userid, field, value)
+}
+
+// SetUserDataContext is like SetUserData but aborts the request when ctx is done
+func (api *Provisioning) SetUserDataContext(ctx context.Context, userid string, field UserField, value string) error {
+	body := map[string]string{"key": string(field), "value": value}
+	reader := new(bytes.Buffer)
+	json.NewEncoder(reader).Encode(body)
+
+	url := endpoint + "/users/" + userid + "?format=json"
+	_, err := ocs.DoContext[json.RawMessage](ctx, &api.ocs, http.MethodPut, url, reader, true)
+	return wrapError("editing the user", err, editUserErrors)
+}
+
+// SetUserDisplayName changes the display name of the user
+func (api *Provisioning) SetUserDisplayName(userid, displayName string) error {
+	return api.SetUserDisplayNameContext(context.Background(), userid, displayName)
+}
+
+// SetUserDisplayNameContext is like SetUserDisplayName but aborts the request when ctx is done
+func (api *Provisioning) SetUserDisplayNameContext(ctx context.Context, userid, displayName string) error {
+	return api.SetUserDataContext(ctx, userid, FieldDisplayName, displayName)
+}
+
+// SetUserEmail changes the email address of the user
+func (api *Provisioning) SetUserEmail(userid, email string) error {
+	return api.SetUserEmailContext(context.Background(), userid, email)
+}
+
+// SetUserEmailContext is like SetUserEmail but aborts the request when ctx is done
+func (api *Provisioning) SetUserEmailContext(ctx context.Context, userid, email string) error {
+	return api.SetUserDataContext(ctx, userid, FieldEmail, email)
+}
+
+// SetUserQuota changes the quota of the user
+// The quota is given in bytes or human readable ("5 GB"), "none" removes the limit
+// and "default" resets it to the default quota of the instance
+// See ParseQuota for the accepted formats
+func (api *Provisioning) SetUserQuota(userid, quota string) error {
+	return api.SetUserQuotaContext(context.Background(), userid, quota)
+}
+
+// SetUserQuotaContext is like SetUserQuota but aborts the request when ctx is done
+func (api *Provisioning) SetUserQuotaContext(ctx context.Context, userid, quota string) error {
+	return api.SetUserDataContext(ctx, userid, FieldQuota, quota)
+}
+
+// SetUserPassword changes the password of the user
+// Returns ErrInvalidPassword when the password does not match the password policy
+func (api *Provisioning) SetUserPassword(userid, password string) error {
+	return api.SetUserPasswordContext(context.Background(), userid, password)
+}
+
+// SetUserPasswordContext is like SetUserPassword but aborts the request when ctx is done
+func (api *Provisioning) SetUserPasswordContext(ctx context.Context, userid, password string) error {
+	return api.SetUserDataContext(ctx, userid, FieldPassword, password)
+}
+
+// SetUserLanguage changes the language of the user, e.g. "de"
+func (api *Provisioning) SetUserLanguage(userid, language string) error {
+	return api.SetUserLanguageContext(context.Background(), userid, language)
+}
+
+// SetUserLanguageContext is like SetUserLanguage but aborts the request when ctx is done
+func (api *Provisioning) SetUserLanguageContext(ctx context.Context, userid, language string) error {
+	return api.SetUserDataContext(ctx, userid, FieldLanguage, language)
+}
+
+// SetUserLocale changes the locale of the user, e.g. "de_DE"
+func (api *Provisioning) SetUserLocale(userid, locale string) error {
+	return api.SetUserLocaleContext(context.Background(), userid, locale)
+}
+
+// SetUserLocaleContext is like SetUserLocale but aborts the request when ctx is done
+func (api *Provisioning) SetUserLocaleContext(ctx context.Context, userid, locale string) error {
+	return api.SetUserDataContext(ctx, userid, FieldLocale, locale)
+}
+
+// SetUserPhone changes the phone number of the user
+func (api *Provisioning) SetUserPhone(userid, phone string) error {
+	return api.SetUserPhoneContext(context.Background(), userid, phone)
+}
+
+// SetUserPhoneContext is like SetUserPhone but aborts the request when ctx is done
+func (api *Provisioning) SetUserPhoneContext(ctx context.Context, userid, phone string) error {
+	return api.SetUserDataContext(ctx, userid, FieldPhone, phone)
+}
+
+// SetUserAddress changes the postal address of the user
+func (api *Provisioning) SetUserAddress(userid, address string) error {
+	return api.SetUserAddressContext(context.Background(), userid, address)
+}
+
+// SetUserAddressContext is like SetUserAddress but aborts the request when ctx is done
+func (api *Provisioning) SetUserAddressContext(ctx context.Context, userid, address string) error {
+	return api.SetUserDataContext(ctx, userid, FieldAddress, address)
+}
+
+// SetUserWebsite changes the website of the user
+func (api *Provisioning) SetUserWebsite(userid, website string) error {
+	return api.SetUserWebsiteContext(context.Background(), userid, website)
+}
+
+// SetUserWebsiteContext is like SetUserWebsite but aborts the request when ctx is done
+func (api *Provisioning) SetUserWebsiteContext(ctx context.Context, userid, website string) error {
+	return api.SetUserDataContext(ctx, userid, FieldWebsite, website)
+}
+
+// SetUserTwitter changes the twitter handle of the user
+func (api *Provisioning) SetUserTwitter(userid, twitter string) error {
+	return api.SetUserTwitterContext(context.Background(), userid, twitter)
+}
+
+// SetUserTwitterContext is like SetUserTwitter but aborts the request when ctx is done
+func (api *Provisioning) SetUserTwitterContext(ctx context.Context, userid, twitter string) error {
+	return api.SetUserDataContext(ctx, userid, FieldTwitter, twitter)
+}
@@ -0,0 +1,156 @@
+package provisioning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/nextcloud/nextcloudgo/ocs"
+)
+
+// DefaultPageSize is the number of users requested per page when no page size is given
+const DefaultPageSize = 100
+
+type userDetailsList struct {
+	Users []User
+}
+
+// UnmarshalJSON decodes the users which are keyed by their id.
+// The server sends an empty array instead of an object when there are no users.
+func (l *userDetailsList) UnmarshalJSON(b []byte) error {
+	var data struct {
+		Users json.RawMessage `json:"users"`
+	}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+	if len(data.Users) == 0 || bytes.Equal(data.Users, []byte("[]")) {
+		l.Users = []User{}
+		return nil
+	}
+
+	var users map[string]User
+	if err := json.Unmarshal(data.Users, &users); err != nil {
+		return err
+	}
+
+	l.Users = make([]User, 0, len(users))
+	for id, user := range users {
+		if user.ID == "" {
+			user.ID = id
+		}
+		l.Users = append(l.Users, user)
+	}
+	sort.Slice(l.Users, func(i, j int) bool { return l.Users[i].ID < l.Users[j].ID })
+	return nil
+}
+
+// GetUsersDetails returns the full data of the users matching the given search
+// The search is with wildcards on both ends
+// Setting offset and limit to 0 will return all users
+func (api *Provisioning) GetUsersDetails(search string, offset, limit int) ([]User, error) {
+	return api.GetUsersDetailsContext(context.Background(), search, offset, limit)
+}
+
+// GetUsersDetailsContext is like GetUsersDetails but aborts the request when ctx is done
+func (api *Provisioning) GetUsersDetailsContext(ctx context.Context, search string, offset, limit int) ([]User, error) {
+	query := url.Values{"format": {"json"}}
+	if search != "" {
+		query.Set("search", search)
+	}
+	if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	res, err := ocs.DoContext[userDetailsList](ctx, &api.ocs, http.MethodGet, endpoint+"/users/details?"+query.Encode(), nil, true)
+	if err != nil {
+		return []User{}, wrapError("searching for users", err, nil)
+	}
+
+	return res.Data.Users, nil
+}
+
+// UserIterator walks through the users of the server page by page.
+// Use it like a bufio.Scanner:
+//
+//	it := api.IterateUsers("", 500)
+//	for it.Next() {
+//		user := it.User()
+//	}
+//	if err := it.Err(); err != nil {
+//		// handle the error
+//	}
+type UserIterator struct {
+	api      *Provisioning
+	ctx      context.Context
+	search   string
+	pageSize int
+
+	offset int
+	page   []User
+	user   User
+	done   bool
+	err    error
+}
+
+// IterateUsers returns an iterator over the full data of all users matching the search.
+// Each page holds up to pageSize users, DefaultPageSize is used when pageSize is 0.
+func (api *Provisioning) IterateUsers(search string, pageSize int) *UserIterator {
+	return api.IterateUsersContext(context.Background(), search, pageSize)
+}
+
+// IterateUsersContext is like IterateUsers but aborts the requests when ctx is done
+func (api *Provisioning) IterateUsersContext(ctx context.Context, search string, pageSize int) *UserIterator {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	return &UserIterator{api: api, ctx: ctx, search: search, pageSize: pageSize}
+}
+
+// Next advances to the next user and fetches the next page when needed.
+// It returns false when all users were returned or an error occured.
+func (it *UserIterator) Next() bool {
+	if len(it.page) == 0 && !it.done {
+		it.fetch()
+	}
+	if len(it.page) == 0 {
+		return false
+	}
+
+	it.user, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// User returns the current user
+func (it *UserIterator) User() User {
+	return it.user
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *UserIterator) Err() error {
+	return it.err
+}
+
+func (it *UserIterator) fetch() {
+	users, err := it.api.GetUsersDetailsContext(it.ctx, it.search, it.offset, it.pageSize)
+	if err != nil {
+		it.err = err
+		it.done = true
+		return
+	}
+
+	// The server skips users whose data can not be loaded, so a short page
+	// does not mean the end was reached. Only an empty page does.
+	if len(users) == 0 {
+		it.done = true
+	}
+	it.offset += it.pageSize
+	it.page = users
+}
@@ -2,68 +2,63 @@ package provisioning
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/nextcloud/nextcloudgo/ocs"
 )
 
-var (
-	// ErrUserDoesNotExist when the user does not exist
-	ErrUserDoesNotExist = errors.New("User does not exist")
-	// ErrUserAlreadyExists when the user already exists
-	ErrUserAlreadyExists = errors.New("User already exists")
-)
-
-// TODO missing GetUserData
-// TODO missing SetUserData
+type userList struct {
+	Users []string `json:"users"`
+}
 
 // CreateUser creates the user on the server if it does not exist yet
 // Returns ErrUserAlreadyExists when the user already exists
+// Returns ErrInvalidPassword when the password does not match the password policy
 func (api *Provisioning) CreateUser(userid, password string) error {
+	return api.CreateUserContext(context.Background(), userid, password)
+}
+
+// CreateUserContext is like CreateUser but aborts the request when ctx is done
+func (api *Provisioning) CreateUserContext(ctx context.Context, userid, password string) error {
 	body := map[string]string{"userid": userid, "password": password}
 	reader := new(bytes.Buffer)
 	json.NewEncoder(reader).Encode(body)
 
 	url := endpoint + "/users?format=json"
-	content, status, err := api.ocs.RequestWithBody(http.MethodPost, url, reader, true)
-	if err != nil {
-		return err
-	}
-
-	if status != http.StatusOK {
-		if ocs.ValidateStatusCode(content, 102) {
-			return ErrUserAlreadyExists
-		}
-		return errors.New("An error occured while creating the user")
-	}
-
-	return nil
+	_, err := ocs.DoContext[json.RawMessage](ctx, &api.ocs, http.MethodPost, url, reader, true)
+	return wrapError("creating the user", err, createUserErrors)
 }
 
 // DeleteUser deletes the given user from the server
 // The current user can not be deleted
 // Returns ErrUserDoesNotExist when the user does not exist
 func (api *Provisioning) DeleteUser(userid string) error {
-	url := endpoint + "/users/" + userid + "?format=json"
-	_, status, err := api.ocs.Request(http.MethodDelete, url, true)
-	if err != nil {
-		return err
-	}
+	return api.DeleteUserContext(context.Background(), userid)
+}
 
-	if status != http.StatusOK {
-		return errors.New("An error occured while deleting the user")
+// DeleteUserContext is like DeleteUser but aborts the request when ctx is done
+func (api *Provisioning) DeleteUserContext(ctx context.Context, userid string) error {
+	if userid == api.nc.User {
+		return ErrDeleteCurrentUser
 	}
 
-	return nil
+	url := endpoint + "/users/" + userid + "?format=json"
+	_, err := ocs.DoContext[json.RawMessage](ctx, &api.ocs, http.MethodDelete, url, nil, true)
+	return wrapError("deleting the user", err, changeUserErrors)
 }
 
 // GetUsers returns all users from the server matching the given search
 // The search is with wildcards on both ends
 // Setting start and limit to 0 will return all users
 func (api *Provisioning) GetUsers(search string, start, limit int) ([]string, error) {
+	return api.GetUsersContext(context.Background(), search, start, limit)
+}
+
+// GetUsersContext is like GetUsers but aborts the request when ctx is done
+func (api *Provisioning) GetUsersContext(ctx context.Context, search string, start, limit int) ([]string, error) {
 	url := endpoint + "/users?format=json"
 	if search != "" {
 		url += "&search=" + search
@@ -75,21 +70,22 @@ func (api *Provisioning) GetUsers(search string, start, limit int) ([]string, er
 		url += "&limit=" + strconv.Itoa(limit)
 	}
 
-	content, status, err := api.ocs.Request(http.MethodGet, url, true)
+	res, err := ocs.DoContext[userList](ctx, &api.ocs, http.MethodGet, url, nil, true)
 	if err != nil {
-		return []string{}, err
-	}
-
-	if status != http.StatusOK {
-		return []string{}, errors.New("An error occured while searching for groups")
+		return []string{}, wrapError("searching for users", err, nil)
 	}
 
-	return ocs.GetStringList(content, []string{"ocs", "data", "users"})
+	return res.Data.Users, nil
 }
 
 // UserExists checks whether a user exists on the server
 func (api *Provisioning) UserExists(userid string) (bool, error) {
-	users, err := api.GetUsers(userid, 0, 0)
+	return api.UserExistsContext(context.Background(), userid)
+}
+
+// UserExistsContext is like UserExists but aborts the request when ctx is done
+func (api *Provisioning) UserExistsContext(ctx context.Context, userid string) (bool, error) {
+	users, err := api.GetUsersContext(ctx, userid, 0, 0)
 	if err != nil {
 		return false, err
 	}
@@ -104,93 +100,90 @@ func (api *Provisioning) UserExists(userid string) (bool, error) {
 }
 
 // EnableUser enables a disabled user
+// Returns ErrUserDoesNotExist when the user does not exist
 func (api *Provisioning) EnableUser(userid string) error {
-	return api.changeUserState(userid, "enable")
+	return api.EnableUserContext(context.Background(), userid)
+}
+
+// EnableUserContext is like EnableUser but aborts the request when ctx is done
+func (api *Provisioning) EnableUserContext(ctx context.Context, userid string) error {
+	return api.changeUserState(ctx, userid, "enable")
 }
 
 // DisableUser disables an enabled user
+// Returns ErrUserDoesNotExist when the user does not exist
 func (api *Provisioning) DisableUser(userid string) error {
-	return api.changeUserState(userid, "disable")
+	return api.DisableUserContext(context.Background(), userid)
+}
 
+// DisableUserContext is like DisableUser but aborts the request when ctx is done
+func (api *Provisioning) DisableUserContext(ctx context.Context, userid string) error {
+	return api.changeUserState(ctx, userid, "disable")
 }
 
-func (api *Provisioning) changeUserState(userid string, state string) error {
+func (api *Provisioning) changeUserState(ctx context.Context, userid string, state string) error {
 	url := endpoint + "/users/" + userid + "/" + state + "?format=json"
 
-	_, status, err := api.ocs.Request(http.MethodPut, url, true)
-	if err != nil {
-		return err
-	}
-
-	if status != http.StatusOK {
-		if state == "enable" {
-			return errors.New("An error occured while enabling the user")
-		}
-
-		return errors.New("An error occured while disabling the user")
+	_, err := ocs.DoContext[json.RawMessage](ctx, &api.ocs, http.MethodPut, url, nil, true)
+	if state == "enable" {
+		return wrapError("enabling the user", err, changeUserErrors)
 	}
 
-	return nil
+	return wrapError("disabling the user", err, changeUserErrors)
 }
 
 // AddUserToGroup adds the user to the given group
 // Returns ErrGroupDoesNotExist when the group does not exist
 // Returns ErrUserDoesNotExist when the user does not exist
 func (api *Provisioning) AddUserToGroup(userid, groupid string) error {
-	return api.changeUserGroupMemberState(userid, groupid, http.MethodPost)
+	return api.AddUserToGroupContext(context.Background(), userid, groupid)
+}
+
+// AddUserToGroupContext is like AddUserToGroup but aborts the request when ctx is done
+func (api *Provisioning) AddUserToGroupContext(ctx context.Context, userid, groupid string) error {
+	return api.changeUserGroupMemberState(ctx, userid, groupid, http.MethodPost)
 }
 
 // RemoveUserFromGroup removes the user from the given group
 // Returns ErrGroupDoesNotExist when the group does not exist
 // Returns ErrUserDoesNotExist when the user does not exist
 func (api *Provisioning) RemoveUserFromGroup(userid, groupid string) error {
-	return api.changeUserGroupMemberState(userid, groupid, http.MethodDelete)
+	return api.RemoveUserFromGroupContext(context.Background(), userid, groupid)
+}
+
+// RemoveUserFromGroupContext is like RemoveUserFromGroup but aborts the request when ctx is done
+func (api *Provisioning) RemoveUserFromGroupContext(ctx context.Context, userid, groupid string) error {
+	return api.changeUserGroupMemberState(ctx, userid, groupid, http.MethodDelete)
 }
 
-func (api *Provisioning) changeUserGroupMemberState(userid, groupid, method string) error {
+func (api *Provisioning) changeUserGroupMemberState(ctx context.Context, userid, groupid, method string) error {
 	body := map[string]string{"groupid": groupid}
 	reader := new(bytes.Buffer)
 	json.NewEncoder(reader).Encode(body)
 
 	url := endpoint + "/users/" + userid + "/groups?format=json"
-	content, status, err := api.ocs.RequestWithBody(method, url, reader, true)
-	if err != nil {
-		return err
+	_, err := ocs.DoContext[json.RawMessage](ctx, &api.ocs, method, url, reader, true)
+	if method == http.MethodPost {
+		return wrapError("adding the user to the group", err, memberErrors)
 	}
 
-	if status != http.StatusOK {
-		if ocs.ValidateStatusCode(content, 102) {
-			return ErrGroupDoesNotExist
-		}
-		if ocs.ValidateStatusCode(content, 103) {
-			return ErrUserDoesNotExist
-		}
-		if method == http.MethodPost {
-			return errors.New("An error occured while adding the user to the group")
-		}
-
-		return errors.New("An error occured while removing the user from the group")
-	}
-
-	return nil
+	return wrapError("removing the user from the group", err, memberErrors)
 }
 
 // GetUserGroups returns all groups the user is a member of
+// Returns ErrUserDoesNotExist when the user does not exist
 func (api *Provisioning) GetUserGroups(userid string) ([]string, error) {
+	return api.GetUserGroupsContext(context.Background(), userid)
+}
+
+// GetUserGroupsContext is like GetUserGroups but aborts the request when ctx is done
+func (api *Provisioning) GetUserGroupsContext(ctx context.Context, userid string) ([]string, error) {
 	url := endpoint + "/users/" + userid + "/groups?format=json"
 
-	content, status, err := api.ocs.Request(http.MethodGet, url, true)
+	res, err := ocs.DoContext[groupList](ctx, &api.ocs, http.MethodGet, url, nil, true)
 	if err != nil {
-		return []string{}, err
-	}
-
-	if status == http.StatusNotFound {
-		return []string{}, ErrUserDoesNotExist
-	}
-
-	if status != http.StatusOK {
-		return []string{}, errors.New("An error occured while searching for groups")
+		return []string{}, wrapError("getting the groups of the user", err, userErrors)
 	}
 
-	return ocs.GetStringList(content, []string{"ocs", "data", "groups"})
+	return res.Data.Groups, nil
 }
@@ -0,0 +1,249 @@
+package provisioning
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nextcloud/nextcloudgo"
+	"github.com/nextcloud/nextcloudgo/ocs"
+)
+
+func TestCreateUserErrors(t *testing.T) {
+	codes := map[string]int{"existing": 102, "weak": 107, "nogroup": 104, "broken": 103}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			UserID string `json:"userid"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"ocs":{"meta":{"status":"failure","statuscode":%d,"message":"failed for %s"},"data":[]}}`, codes[body.UserID], body.UserID)
+	}))
+	defer ts.Close()
+
+	nc := nextcloudgo.NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"}
+	api := New(nc)
+
+	for userid, expected := range map[string]error{
+		"existing": ErrUserAlreadyExists,
+		"weak":     ErrInvalidPassword,
+		"nogroup":  ErrGroupDoesNotExist,
+	} {
+		err := api.CreateUser(userid, "123456")
+		if !errors.Is(err, expected) {
+			t.Errorf("%s: expected %v, got %v", userid, expected, err)
+		}
+
+		var ocsErr *ocs.Error
+		if !errors.As(err, &ocsErr) {
+			t.Errorf("%s: error should wrap the *ocs.Error", userid)
+		} else if ocsErr.StatusCode != codes[userid] || ocsErr.Message != "failed for "+userid {
+			t.Errorf("%s: unexpected OCS error %v", userid, ocsErr)
+		}
+	}
+
+	err := api.CreateUser("broken", "123456")
+	var provisioningErr *Error
+	if !errors.As(err, &provisioningErr) || provisioningErr.Err != nil {
+		t.Errorf("Unknown status codes should not map to a sentinel, got %v", err)
+	} else if err.Error() != "An error occured while creating the user" {
+		t.Error(err.Error())
+	}
+}
+
+func TestDeleteUserErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/ocs/v2.php/cloud/users/unknown" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, `{"ocs":{"meta":{"status":"failure","statuscode":101,"message":""},"data":[]}}`)
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintln(w, `{"ocs":{"meta":{"status":"failure","statuscode":403,"message":"Logged in user must be an admin"},"data":[]}}`)
+	}))
+	defer ts.Close()
+
+	nc := nextcloudgo.NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"}
+	api := New(nc)
+
+	if err := api.DeleteUser("unknown"); !errors.Is(err, ErrUserDoesNotExist) {
+		t.Errorf("Expected ErrUserDoesNotExist, got %v", err)
+	}
+	if err := api.DeleteUser("alice"); !errors.Is(err, ErrInsufficientPermissions) {
+		t.Errorf("Expected ErrInsufficientPermissions, got %v", err)
+	}
+	if err := api.DeleteUser("admin"); err != ErrDeleteCurrentUser {
+		t.Errorf("Expected ErrDeleteCurrentUser, got %v", err)
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/ocs/v2.php/cloud/users/alice", "/ocs/v2.php/cloud/user":
+			fmt.Fprintln(w, `{"ocs":{"meta":{"status":"ok","statuscode":200,"message":"OK"},"data":{"enabled":true,"id":"alice","lastLogin":1600000000000,"backend":"Database","quota":{"free":1.0e10,"used":2048,"total":10000002048,"relative":0,"quota":-3},"email":null,"displayname":"Alice","phone":"+49 123","address":"","website":"https://alice.example.com","twitter":"","groups":["admin","sales"],"language":"de","locale":"de_DE"}}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintln(w, `{"ocs":{"meta":{"status":"failure","statuscode":404,"message":"User does not exist"},"data":[]}}`)
+		}
+	}))
+	defer ts.Close()
+
+	nc := nextcloudgo.NextcloudGo{ServerURL: ts.URL, User: "alice", Password: "alice"}
+	api := New(nc)
+
+	u, err := api.GetUser("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != "alice" || u.DisplayName != "Alice" || u.Email != "" || !u.Enabled || u.Backend != "Database" {
+		t.Errorf("User was not decoded correctly: %+v", u)
+	}
+	if u.Quota.Free != 10000000000 || u.Quota.Used != 2048 {
+		t.Errorf("Quota was not decoded correctly: %+v", u.Quota)
+	}
+	if !reflect.DeepEqual(u.Groups, []string{"admin", "sales"}) {
+		t.Error("Groups were not decoded correctly")
+	}
+	if !u.LastLogin.Equal(time.Unix(1600000000, 0)) {
+		t.Error("Last login was not decoded correctly")
+	}
+
+	current, err := api.GetCurrentUser()
+	if err != nil || current.ID != "alice" {
+		t.Errorf("Current user was not returned, got %v", err)
+	}
+
+	if _, err := api.GetUser("bob"); !errors.Is(err, ErrUserDoesNotExist) {
+		t.Errorf("Expected ErrUserDoesNotExist, got %v", err)
+	}
+}
+
+func TestUserWithoutStorage(t *testing.T) {
+	// The server sends an empty array when it can not read the storage of the user
+	var u User
+	if err := json.Unmarshal([]byte(`{"id":"bob","displayname":"Bob","enabled":true,"quota":[],"groups":[]}`), &u); err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != "bob" || u.DisplayName != "Bob" || !u.Enabled || u.Quota != (Quota{}) {
+		t.Errorf("User was not decoded correctly: %+v", u)
+	}
+}
+
+func TestSetUserData(t *testing.T) {
+	var key, value string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		key, value = body["key"], body["value"]
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodPut || r.URL.Path != "/ocs/v2.php/cloud/users/alice" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, `{"ocs":{"meta":{"status":"failure","statuscode":101,"message":""},"data":[]}}`)
+			return
+		}
+		if key == "email" && value == "invalid" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintln(w, `{"ocs":{"meta":{"status":"failure","statuscode":102,"message":""},"data":[]}}`)
+			return
+		}
+		fmt.Fprintln(w, `{"ocs":{"meta":{"status":"ok","statuscode":200,"message":"OK"},"data":[]}}`)
+	}))
+	defer ts.Close()
+
+	nc := nextcloudgo.NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"}
+	api := New(nc)
+
+	if err := api.SetUserQuota("alice", "5 GB"); err != nil {
+		t.Fatal(err)
+	}
+	if key != "quota" || value != "5 GB" {
+		t.Errorf("Unexpected key/value %s=%s", key, value)
+	}
+
+	if err := api.SetUserEmail("alice", "invalid"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
+	}
+	if err := api.SetUserDisplayName("bob", "Bob"); !errors.Is(err, ErrUserDoesNotExist) {
+		t.Errorf("Expected ErrUserDoesNotExist, got %v", err)
+	}
+}
+
+func TestIterateUsers(t *testing.T) {
+	all := []string{"alice", "bob", "carol", "dave", "eve"}
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/ocs/v2.php/cloud/users/details" || r.URL.Query().Get("search") != "e" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		w.Header().Set("Content-Type", "application/json")
+		if offset >= len(all) {
+			fmt.Fprintln(w, `{"ocs":{"meta":{"status":"ok","statuscode":200,"message":"OK"},"data":{"users":[]}}}`)
+			return
+		}
+
+		users := map[string]interface{}{}
+		for _, id := range all[offset:min(offset+limit, len(all))] {
+			users[id] = map[string]interface{}{"id": id, "displayname": strings.ToUpper(id), "enabled": true}
+		}
+		data, _ := json.Marshal(users)
+		fmt.Fprintf(w, `{"ocs":{"meta":{"status":"ok","statuscode":200,"message":"OK"},"data":{"users":%s}}}`, data)
+	}))
+	defer ts.Close()
+
+	nc := nextcloudgo.NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"}
+	api := New(nc)
+
+	var ids []string
+	it := api.IterateUsers("e", 2)
+	for it.Next() {
+		u := it.User()
+		if u.DisplayName != strings.ToUpper(u.ID) {
+			t.Errorf("Unexpected display name %s for %s", u.DisplayName, u.ID)
+		}
+		ids = append(ids, u.ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, all) {
+		t.Errorf("Unexpected users %v", ids)
+	}
+	if requests != 4 {
+		t.Errorf("Expected 3 pages and one empty page, got %d requests", requests)
+	}
+}
+
+func TestIterateUsersError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	nc := nextcloudgo.NextcloudGo{ServerURL: ts.URL, User: "admin", Password: "admin"}
+	api := New(nc)
+
+	it := api.IterateUsers("", 0)
+	if it.Next() {
+		t.Error("Iterator should stop on server error")
+	}
+	if err := it.Err(); err == nil || err.Error() != "An error occured while searching for users" {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
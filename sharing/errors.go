@@ -0,0 +1,87 @@
+package sharing
+
+import (
+	"errors"
+
+	"github.com/nextcloud/nextcloudgo/ocs"
+)
+
+var (
+	// ErrShareNotFound when the share does not exist or is not visible to the user
+	ErrShareNotFound = errors.New("Share does not exist")
+	// ErrFileNotFound when the file or folder to share does not exist
+	ErrFileNotFound = errors.New("File or folder does not exist")
+	// ErrInvalidInput when the server rejected the given values, e.g. an unknown share type,
+	// a password that does not match the policy or an expiration date in the past.
+	// The reason is available as message of the *ocs.Error
+	ErrInvalidInput = errors.New("Invalid input data")
+
+	// ErrUnauthorized when the login was not accepted by the server
+	ErrUnauthorized = errors.New("Unauthorized")
+	// ErrInsufficientPermissions when the logged in user is not allowed to perform the action,
+	// e.g. because public uploads or resharing are disabled
+	ErrInsufficientPermissions = errors.New("Insufficient permissions")
+)
+
+// Error is returned when the server rejected a request.
+// It wraps the matching sentinel error (if any) and the *ocs.Error of the response,
+// so both errors.Is(err, ErrShareNotFound) and errors.As(err, &ocsErr) work.
+type Error struct {
+	// Op describes the failed operation, e.g. "creating the share"
+	Op string
+	// Err is the sentinel error of the status code, nil for unknown failures
+	Err error
+	// OCS is the error of the response
+	OCS *ocs.Error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return "An error occured while " + e.Op
+}
+
+// Unwrap returns the sentinel and the OCS error
+func (e *Error) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.OCS}
+	}
+	return []error{e.Err, e.OCS}
+}
+
+// statusErrors maps the OCS status codes of an endpoint to sentinel errors.
+// OCS v2 reports 997 and 998 as 401 and 404, so both variants are listed.
+type statusErrors map[int]error
+
+var commonErrors = statusErrors{
+	400: ErrInvalidInput,
+	401: ErrUnauthorized,
+	997: ErrUnauthorized,
+	403: ErrInsufficientPermissions,
+}
+
+var (
+	pathErrors  = statusErrors{404: ErrFileNotFound, 998: ErrFileNotFound}
+	shareErrors = statusErrors{404: ErrShareNotFound, 998: ErrShareNotFound}
+)
+
+// wrapError turns an *ocs.Error into an *Error for the given operation.
+// Transport and decoding errors are returned unchanged.
+func wrapError(op string, err error, codes statusErrors) error {
+	var e *ocs.Error
+	if !errors.As(err, &e) {
+		return err
+	}
+
+	code := e.StatusCode
+	if code == 0 {
+		code = e.HTTPStatus
+	}
+
+	sentinel := codes[code]
+	if sentinel == nil {
+		sentinel = commonErrors[code]
+	}
+	return &Error{Op: op, Err: sentinel, OCS: e}
+}
@@ -0,0 +1,174 @@
+package sharing
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/nextcloud/nextcloudgo/files"
+	"github.com/nextcloud/nextcloudgo/ocs"
+)
+
+// ListOptions filter the shares returned by GetShares, the zero value returns the shares of the logged in user
+type ListOptions struct {
+	// Path only returns the shares of this file or folder
+	Path string
+	// Reshares also returns the shares others created of the files of the user
+	Reshares bool
+	// Subfiles returns the shares of the files in the folder at Path instead of the folder itself
+	Subfiles bool
+	// SharedWithMe returns the shares others created for the user instead
+	SharedWithMe bool
+	// IncludeTags fills the Tags of the shares, e.g. to find favorites
+	IncludeTags bool
+}
+
+func (options ListOptions) query() url.Values {
+	query := url.Values{"format": {"json"}}
+	if options.Path != "" {
+		query.Set("path", options.Path)
+	}
+	for name, enabled := range map[string]bool{
+		"reshares":       options.Reshares,
+		"subfiles":       options.Subfiles,
+		"shared_with_me": options.SharedWithMe,
+		"include_tags":   options.IncludeTags,
+	} {
+		if enabled {
+			query.Set(name, "true")
+		}
+	}
+	return query
+}
+
+// GetShares returns the shares matching the options
+// Returns ErrFileNotFound when the path does not exist and ErrInvalidInput when Subfiles is used for a file
+func (sharing *Sharing) GetShares(options ListOptions) ([]Share, error) {
+	return sharing.GetSharesContext(context.Background(), options)
+}
+
+// GetSharesContext is like GetShares but aborts the request when ctx is done
+func (sharing *Sharing) GetSharesContext(ctx context.Context, options ListOptions) ([]Share, error) {
+	url := endpoint + "/shares?" + options.query().Encode()
+	res, err := ocs.DoContext[[]Share](ctx, &sharing.ocs, http.MethodGet, url, nil, true)
+	if err != nil {
+		return []Share{}, wrapError("listing the shares", err, pathErrors)
+	}
+	if res.Data == nil {
+		return []Share{}, nil
+	}
+	return res.Data, nil
+}
+
+// GetSharesForFile returns the shares of the file or folder with the given id, which the user
+// created or which others created of the files of the user.
+// The id is resolved to a path with the DAV search.
+// Returns ErrFileNotFound when the user has no file with the id
+func (sharing *Sharing) GetSharesForFile(fileID int64) ([]Share, error) {
+	return sharing.GetSharesForFileContext(context.Background(), fileID)
+}
+
+// GetSharesForFileContext is like GetSharesForFile but aborts the requests when ctx is done
+func (sharing *Sharing) GetSharesForFileContext(ctx context.Context, fileID int64) ([]Share, error) {
+	query := files.NewQuery("/").Where(files.Equal(files.FieldFileID, strconv.FormatInt(fileID, 10))).Limit(1)
+	found, err := sharing.files.SearchContext(ctx, query)
+	if err != nil {
+		return []Share{}, err
+	}
+	if len(found) == 0 {
+		return []Share{}, ErrFileNotFound
+	}
+	return sharing.GetSharesContext(ctx, ListOptions{Path: found[0].Path, Reshares: true})
+}
+
+// ShareIterator walks through shares and requests the lists of shares one after the other.
+// Every share is returned once. Use it like a bufio.Scanner:
+//
+//	it := api.IterateShares()
+//	for it.Next() {
+//		share := it.Share()
+//	}
+//	if err := it.Err(); err != nil {
+//		// handle the error
+//	}
+type ShareIterator struct {
+	ctx context.Context
+
+	// pending are the lists that are still to be requested
+	pending []shareList
+	seen    map[int]bool
+	page    []Share
+	share   Share
+	err     error
+}
+
+// shareList is a list of shares requested with the login of an account
+type shareList struct {
+	sharing *Sharing
+	options ListOptions
+}
+
+// IterateShares returns an iterator over all shares the logged in user can see: the shares of the user,
+// the ones others created of the files of the user and the ones shared with the user
+func (sharing *Sharing) IterateShares() *ShareIterator {
+	return sharing.IterateSharesContext(context.Background())
+}
+
+// IterateSharesContext is like IterateShares but aborts the requests when ctx is done
+func (sharing *Sharing) IterateSharesContext(ctx context.Context) *ShareIterator {
+	return &ShareIterator{
+		ctx:     ctx,
+		pending: []shareList{{sharing, ListOptions{Reshares: true}}, {sharing, ListOptions{SharedWithMe: true}}},
+		seen:    map[int]bool{},
+	}
+}
+
+// IterateInstanceShares returns an iterator over the shares of all given accounts, e.g. every
+// link share of the company for an audit. The server has no endpoint that lists the shares of
+// all users, not even for admins, so every user has to be passed with their own login, e.g. an
+// app password. Each account lists the shares it created and the ones others created of its files,
+// so with an account of every user each share of the instance is returned once.
+func IterateInstanceShares(accounts ...Sharing) *ShareIterator {
+	return IterateInstanceSharesContext(context.Background(), accounts...)
+}
+
+// IterateInstanceSharesContext is like IterateInstanceShares but aborts the requests when ctx is done
+func IterateInstanceSharesContext(ctx context.Context, accounts ...Sharing) *ShareIterator {
+	it := &ShareIterator{ctx: ctx, seen: map[int]bool{}}
+	for i := range accounts {
+		it.pending = append(it.pending, shareList{&accounts[i], ListOptions{Reshares: true}})
+	}
+	return it
+}
+
+// Next advances to the next share and requests the next list when needed.
+// It returns false when all shares were returned or an error occured.
+func (it *ShareIterator) Next() bool {
+	for {
+		for len(it.page) > 0 {
+			it.share, it.page = it.page[0], it.page[1:]
+			if !it.seen[it.share.Id] {
+				it.seen[it.share.Id] = true
+				return true
+			}
+		}
+		if len(it.pending) == 0 || it.err != nil {
+			return false
+		}
+
+		list := it.pending[0]
+		it.pending = it.pending[1:]
+		it.page, it.err = list.sharing.GetSharesContext(it.ctx, list.options)
+	}
+}
+
+// Share returns the current share
+func (it *ShareIterator) Share() Share {
+	return it.share
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *ShareIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,179 @@
+package sharing
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/nextcloud/nextcloudgo"
+)
+
+// addForeignShare stores a share created by bob
+func (s *fakeServer) addForeignShare(path, fileOwner, with string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.addShare(map[string]interface{}{
+		"share_type":             TypeUser,
+		"uid_owner":              "bob",
+		"displayname_owner":      "Bob",
+		"uid_file_owner":         fileOwner,
+		"displayname_file_owner": fileOwner,
+		"path":                   path,
+		"permissions":            PermissionRead,
+		"share_with":             with,
+		"share_with_displayname": with,
+	})
+}
+
+// fileIDPattern finds the file id of a search for oc:fileid
+var fileIDPattern = regexp.MustCompile(`<oc:fileid/></d:prop><d:literal>(\d+)</d:literal>`)
+
+// search answers the DAV search for a file id with the path of the file
+func (s *fakeServer) search(w http.ResponseWriter, r *http.Request) {
+	s.searches++
+	body, _ := io.ReadAll(r.Body)
+	var responses strings.Builder
+	if match := fileIDPattern.FindSubmatch(body); match != nil {
+		id, _ := strconv.Atoi(string(match[1]))
+		for p, fileID := range s.fileIDs {
+			if fileID == id {
+				fmt.Fprintf(&responses, `<d:response><d:href>/remote.php/dav/files/alice%s</d:href><d:propstat><d:prop>`+
+					`<oc:fileid>%d</oc:fileid></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`, p, id)
+			}
+		}
+	}
+	w.WriteHeader(http.StatusMultiStatus)
+	fmt.Fprintf(w, `<d:multistatus xmlns:d="DAV:" xmlns:oc="http://owncloud.org/ns">%s</d:multistatus>`, responses.String())
+}
+
+func ids(shares []Share) []int {
+	ids := []int{}
+	for _, share := range shares {
+		ids = append(ids, share.Id)
+	}
+	return ids
+}
+
+func TestGetShares(t *testing.T) {
+	server, api := newFakeServer(t, "28.0.4")
+	for _, p := range []string{"/Projects", "/Projects/Physics", "/Projects/Chemistry"} {
+		if _, err := api.CreateShare(p, TypeGroup, "students", ShareOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server.addForeignShare("/Projects/Physics", "alice", "carol")
+	server.addForeignShare("/Bobs Notes", "bob", "alice")
+	server.tags["/Projects/Physics"] = []string{"_$!<Favorite>!$_"}
+
+	for _, test := range []struct {
+		options  ListOptions
+		expected []int
+	}{
+		{ListOptions{}, []int{1, 2, 3}},
+		{ListOptions{Path: "/Projects/Physics"}, []int{2}},
+		{ListOptions{Path: "/Projects", Subfiles: true}, []int{2, 3}},
+		{ListOptions{Reshares: true}, []int{1, 2, 3, 4}},
+		{ListOptions{SharedWithMe: true}, []int{5}},
+	} {
+		shares, err := api.GetShares(test.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ids(shares), test.expected) {
+			t.Errorf("%+v: expected %v, got %v", test.options, test.expected, ids(shares))
+		}
+	}
+
+	shares, err := api.GetShares(ListOptions{Path: "/Projects/Physics", IncludeTags: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares) != 1 || !reflect.DeepEqual(shares[0].Tags, []string{"_$!<Favorite>!$_"}) {
+		t.Errorf("Unexpected shares %+v", shares)
+	}
+
+	if _, err := api.GetShares(ListOptions{Path: "/missing"}); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("Expected ErrFileNotFound, got %v", err)
+	}
+
+	shares, err = api.GetSharesForFile(int64(server.fileIDs["/Projects/Physics"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids(shares), []int{2, 4}) || shares[1].Initiator != "bob" {
+		t.Errorf("Unexpected shares %+v", shares)
+	}
+	if server.searches != 1 || server.lastQuery.Get("path") != "/Projects/Physics" {
+		t.Errorf("The shares should be listed by path, got %v", server.lastQuery)
+	}
+	if _, err := api.GetSharesForFile(999); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("Expected ErrFileNotFound, got %v", err)
+	}
+}
+
+func TestIterateShares(t *testing.T) {
+	server, api := newFakeServer(t, "28.0.4")
+	if _, err := api.CreateShare("/Projects", TypeLink, "", ShareOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	server.addForeignShare("/Projects", "alice", "carol")
+	server.addForeignShare("/Bobs Notes", "bob", "alice")
+	// A reshare of a file of alice with alice is in both lists, but is returned once
+	server.addForeignShare("/Projects", "alice", "alice")
+
+	var shares []Share
+	it := api.IterateShares()
+	for it.Next() {
+		shares = append(shares, it.Share())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids(shares), []int{1, 2, 4, 3}) {
+		t.Errorf("Unexpected shares %v", ids(shares))
+	}
+
+	server.Close()
+	it = api.IterateShares()
+	if it.Next() || it.Err() == nil {
+		t.Error("The iterator should stop with an error")
+	}
+}
+
+func TestIterateInstanceShares(t *testing.T) {
+	server, alice := newFakeServer(t, "28.0.4")
+	nc, err := nextcloudgo.New(server.URL, "bob", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob := New(nc)
+
+	if _, err := alice.CreateShare("/Projects", TypeLink, "", ShareOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// Listed by alice as the owner of the file and by bob as the one who shared it
+	server.addForeignShare("/Projects", "alice", "carol")
+	server.addForeignShare("/Bobs Notes", "bob", "alice")
+
+	var shares []Share
+	it := IterateInstanceShares(alice, bob)
+	for it.Next() {
+		shares = append(shares, it.Share())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids(shares), []int{1, 2, 3}) {
+		t.Errorf("Unexpected shares %v", ids(shares))
+	}
+	if it := IterateInstanceShares(); it.Next() || it.Err() != nil {
+		t.Error("Expected no shares without accounts")
+	}
+}
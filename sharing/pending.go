@@ -0,0 +1,72 @@
+package sharing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/nextcloud/nextcloudgo"
+	"github.com/nextcloud/nextcloudgo/ocs"
+)
+
+// GetPendingShares returns the shares of other users of the server that the logged in user
+// has not accepted yet. Shares are only pending when auto accepting is disabled on the server.
+// It needs Nextcloud 20
+func (sharing *Sharing) GetPendingShares() ([]Share, error) {
+	return sharing.GetPendingSharesContext(context.Background())
+}
+
+// GetPendingSharesContext is like GetPendingShares but aborts the request when ctx is done
+func (sharing *Sharing) GetPendingSharesContext(ctx context.Context) ([]Share, error) {
+	if err := sharing.sdk.RequireContext(ctx, nextcloudgo.FeaturePendingShares); err != nil {
+		return []Share{}, err
+	}
+
+	url := endpoint + "/shares/pending?format=json"
+	res, err := ocs.DoContext[[]Share](ctx, &sharing.ocs, http.MethodGet, url, nil, true)
+	if err != nil {
+		return []Share{}, wrapError("listing the pending shares", err, nil)
+	}
+	if res.Data == nil {
+		return []Share{}, nil
+	}
+	return res.Data, nil
+}
+
+// AcceptPendingShare accepts the pending share, its file then shows up in the files of the user.
+// It needs Nextcloud 20
+// Returns ErrShareNotFound when the share does not exist or is not pending for the user
+func (sharing *Sharing) AcceptPendingShare(id int) error {
+	return sharing.AcceptPendingShareContext(context.Background(), id)
+}
+
+// AcceptPendingShareContext is like AcceptPendingShare but aborts the request when ctx is done
+func (sharing *Sharing) AcceptPendingShareContext(ctx context.Context, id int) error {
+	if err := sharing.sdk.RequireContext(ctx, nextcloudgo.FeaturePendingShares); err != nil {
+		return err
+	}
+
+	url := endpoint + "/shares/pending/" + strconv.Itoa(id) + "?format=json"
+	_, err := ocs.DoContext[json.RawMessage](ctx, &sharing.ocs, http.MethodPost, url, nil, true)
+	return wrapError("accepting the share", err, shareErrors)
+}
+
+// DeclinePendingShare declines the pending share. The server has no separate endpoint for it,
+// the share is removed from the user like DeleteShare does for shares with the user.
+// It needs Nextcloud 20
+// Returns ErrShareNotFound when the share does not exist
+func (sharing *Sharing) DeclinePendingShare(id int) error {
+	return sharing.DeclinePendingShareContext(context.Background(), id)
+}
+
+// DeclinePendingShareContext is like DeclinePendingShare but aborts the request when ctx is done
+func (sharing *Sharing) DeclinePendingShareContext(ctx context.Context, id int) error {
+	if err := sharing.sdk.RequireContext(ctx, nextcloudgo.FeaturePendingShares); err != nil {
+		return err
+	}
+
+	url := endpoint + "/shares/" + strconv.Itoa(id) + "?format=json"
+	_, err := ocs.DoContext[json.RawMessage](ctx, &sharing.ocs, http.MethodDelete, url, nil, true)
+	return wrapError("declining the share", err, shareErrors)
+}
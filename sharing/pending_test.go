@@ -0,0 +1,86 @@
+package sharing
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/nextcloud/nextcloudgo"
+)
+
+// addPendingShare stores a share by bob with alice that she has not accepted yet
+func (s *fakeServer) addPendingShare(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	share := s.addShare(map[string]interface{}{
+		"share_type":             TypeUser,
+		"uid_owner":              "bob",
+		"displayname_owner":      "Bob",
+		"uid_file_owner":         "bob",
+		"displayname_file_owner": "Bob",
+		"path":                   path,
+		"permissions":            PermissionRead,
+		"share_with":             "alice",
+		"share_with_displayname": "Alice",
+	})
+	share["pending"] = true
+	return s.lastID
+}
+
+func (s *fakeServer) listPending(w http.ResponseWriter) {
+	list := []interface{}{}
+	for id := 1; id <= s.lastID; id++ {
+		if share := s.shares[id]; share != nil && share["pending"] == true {
+			list = append(list, share)
+		}
+	}
+	writeData(w, list)
+}
+
+func TestPendingShares(t *testing.T) {
+	server, api := newFakeServer(t, "28.0.4")
+	first := server.addPendingShare("/Budget.ods")
+	second := server.addPendingShare("/Minutes")
+
+	pending, err := api.GetPendingShares()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids(pending), []int{first, second}) || pending[0].Initiator != "bob" || pending[0].Path != "/Budget.ods" {
+		t.Errorf("Unexpected pending shares %+v", pending)
+	}
+
+	if err := api.AcceptPendingShare(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.AcceptPendingShare(first); !errors.Is(err, ErrShareNotFound) {
+		t.Errorf("Accepting twice should return ErrShareNotFound, got %v", err)
+	}
+	if err := api.DeclinePendingShare(second); err != nil {
+		t.Fatal(err)
+	}
+
+	if pending, err := api.GetPendingShares(); err != nil || len(pending) != 0 {
+		t.Errorf("No share should be pending, got %v %v", pending, err)
+	}
+	if shares, err := api.GetShares(ListOptions{SharedWithMe: true}); err != nil || !reflect.DeepEqual(ids(shares), []int{first}) {
+		t.Errorf("The accepted share should be listed, got %v %v", shares, err)
+	}
+}
+
+func TestPendingSharesUnsupported(t *testing.T) {
+	server, api := newFakeServer(t, "19.0.13")
+	id := server.addPendingShare("/Budget.ods")
+
+	if _, err := api.GetPendingShares(); !errors.Is(err, nextcloudgo.ErrUnsupportedByServer) {
+		t.Errorf("Expected ErrUnsupportedByServer, got %v", err)
+	}
+	if err := api.AcceptPendingShare(id); !errors.Is(err, nextcloudgo.ErrUnsupportedByServer) {
+		t.Errorf("Expected ErrUnsupportedByServer, got %v", err)
+	}
+	if err := api.DeclinePendingShare(id); !errors.Is(err, nextcloudgo.ErrUnsupportedByServer) {
+		t.Errorf("Expected ErrUnsupportedByServer, got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package sharing
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Permissions is the bitmask of what the recipient of a share may do
+type Permissions int
+
+// Permissions of shares, they can be combined with |
+const (
+	PermissionRead   Permissions = 1
+	PermissionUpdate Permissions = 2
+	PermissionCreate Permissions = 4
+	PermissionDelete Permissions = 8
+	PermissionShare  Permissions = 16
+	PermissionAll    Permissions = 31
+)
+
+// ErrInvalidPermissions when the permissions are not allowed for the share type or item,
+// the reason is given by the wrapping error
+var ErrInvalidPermissions = errors.New("Invalid permissions")
+
+// permissionLetters are the letters of the single permissions in the order used by String
+var permissionLetters = []struct {
+	permission Permissions
+	letter     byte
+}{
+	{PermissionRead, 'R'},
+	{PermissionUpdate, 'U'},
+	{PermissionCreate, 'C'},
+	{PermissionDelete, 'D'},
+	{PermissionShare, 'S'},
+}
+
+// Has returns true when all of the given permissions are set
+func (p Permissions) Has(permissions Permissions) bool {
+	return p&permissions == permissions
+}
+
+// Add returns the permissions with the given permissions set
+func (p Permissions) Add(permissions Permissions) Permissions {
+	return p | permissions
+}
+
+// Remove returns the permissions without the given permissions
+func (p Permissions) Remove(permissions Permissions) Permissions {
+	return p &^ permissions
+}
+
+// String returns the letters of the set permissions, e.g. "RUCDS" for PermissionAll and "RS" for read and share
+func (p Permissions) String() string {
+	var b strings.Builder
+	for _, l := range permissionLetters {
+		if p.Has(l.permission) {
+			b.WriteByte(l.letter)
+		}
+	}
+	return b.String()
+}
+
+// ParsePermissions converts letters like "RUCDS" as returned by String into permissions.
+// The letters are case-insensitive and may be in any order.
+func ParsePermissions(s string) (Permissions, error) {
+	var p Permissions
+	for _, c := range strings.ToUpper(s) {
+		found := false
+		for _, l := range permissionLetters {
+			if rune(l.letter) == c {
+				p, found = p.Add(l.permission), true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("Unknown permission %q in %q", c, s)
+		}
+	}
+	return p, nil
+}
+
+// Validate checks the permissions against the rules of the server for the share type.
+// itemType is "file" or "folder", the rules that depend on it are skipped when it is empty.
+// Returns an error wrapping ErrInvalidPermissions
+func (p Permissions) Validate(shareType int, itemType string) error {
+	switch {
+	case p == 0:
+		return fmt.Errorf("%w: at least one permission is required", ErrInvalidPermissions)
+	case p.Remove(PermissionAll) != 0:
+		return fmt.Errorf("%w: unknown permissions %d", ErrInvalidPermissions, int(p.Remove(PermissionAll)))
+	case itemType == "file" && (p.Has(PermissionCreate) || p.Has(PermissionDelete)):
+		return fmt.Errorf("%w: files can not be shared with create or delete permissions", ErrInvalidPermissions)
+	// Link shares of folders may allow uploads only, which is called file drop
+	case shareType == TypeLink && p == PermissionCreate:
+		return nil
+	case !p.Has(PermissionRead):
+		return fmt.Errorf("%w: shares need the read permission", ErrInvalidPermissions)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package sharing
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPermissions(t *testing.T) {
+	p := PermissionRead.Add(PermissionUpdate | PermissionShare)
+	if !p.Has(PermissionRead|PermissionShare) || p.Has(PermissionRead|PermissionCreate) {
+		t.Errorf("Unexpected permissions %s", p)
+	}
+	if p = p.Remove(PermissionUpdate); p != PermissionRead|PermissionShare {
+		t.Errorf("Unexpected permissions %s", p)
+	}
+
+	for permissions, expected := range map[Permissions]string{
+		0:                                "",
+		PermissionAll:                    "RUCDS",
+		PermissionRead | PermissionShare: "RS",
+		PermissionCreate:                 "C",
+	} {
+		if permissions.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, permissions.String())
+		}
+		if parsed, err := ParsePermissions(expected); err != nil || parsed != permissions {
+			t.Errorf("Parsing %q returned %d %v", expected, parsed, err)
+		}
+	}
+	if parsed, err := ParsePermissions("scr"); err != nil || parsed != PermissionRead|PermissionCreate|PermissionShare {
+		t.Errorf("Parsing should ignore case and order, got %s %v", parsed, err)
+	}
+	if _, err := ParsePermissions("RW"); err == nil {
+		t.Error("Unknown letters should be rejected")
+	}
+}
+
+func TestValidatePermissions(t *testing.T) {
+	for _, test := range []struct {
+		permissions Permissions
+		shareType   int
+		itemType    string
+		valid       bool
+	}{
+		{PermissionRead, TypeLink, "file", true},
+		{PermissionRead | PermissionUpdate, TypeLink, "file", true},
+		{PermissionRead | PermissionCreate, TypeLink, "file", false},
+		{PermissionRead | PermissionDelete, TypeUser, "file", false},
+		{PermissionAll, TypeGroup, "folder", true},
+		{PermissionAll, TypeLink, "", true},
+		{PermissionCreate, TypeLink, "folder", true},
+		{PermissionCreate, TypeUser, "folder", false},
+		{PermissionUpdate, TypeMail, "file", false},
+		{0, TypeUser, "folder", false},
+		{64 | PermissionRead, TypeUser, "folder", false},
+	} {
+		err := test.permissions.Validate(test.shareType, test.itemType)
+		if test.valid && err != nil {
+			t.Errorf("%s for type %d on %q should be valid, got %v", test.permissions, test.shareType, test.itemType, err)
+		} else if !test.valid && !errors.Is(err, ErrInvalidPermissions) {
+			t.Errorf("%s for type %d on %q should be invalid, got %v", test.permissions, test.shareType, test.itemType, err)
+		}
+	}
+}
+
+func TestCreateShareInvalidPermissions(t *testing.T) {
+	server, api := newFakeServer(t, "28.0.4")
+
+	_, err := api.CreateShare("/Report.pdf", TypeLink, "", ShareOptions{Permissions: PermissionRead | PermissionCreate})
+	if !errors.Is(err, ErrInvalidPermissions) {
+		t.Errorf("Expected ErrInvalidPermissions, got %v", err)
+	}
+	if _, err := api.CreateShare("/missing", TypeUser, "bob", ShareOptions{Permissions: PermissionAll}); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("Expected ErrFileNotFound, got %v", err)
+	}
+	if len(server.shares) != 0 {
+		t.Error("No share should be created")
+	}
+
+	share, err := api.CreateShare("/Uploads", TypeLink, "", ShareOptions{Permissions: PermissionCreate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if share.Permissions != PermissionCreate {
+		t.Errorf("Unexpected permissions %s", share.Permissions)
+	}
+
+	file, err := api.CreateShare("/Report.pdf", TypeUser, "bob", ShareOptions{Permissions: PermissionRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := api.UpdateSharePermissions(file.Id, PermissionRead|PermissionDelete); !errors.Is(err, ErrInvalidPermissions) {
+		t.Errorf("Expected ErrInvalidPermissions, got %v", err)
+	}
+	if len(server.updates) != 0 {
+		t.Error("Invalid permissions should not be sent")
+	}
+}
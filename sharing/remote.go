@@ -0,0 +1,252 @@
+package sharing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nextcloud/nextcloudgo/ocs"
+)
+
+// RemoteShare is a file or folder shared with the logged in user by a user of another server (federated share)
+type RemoteShare struct {
+	Id int
+	// Type is TypeUser or TypeGroup, depending on whether the share was sent to the user or a group of the user
+	Type int
+	// Remote is the URL of the server the share comes from, e.g. "https://partner.example.com"
+	Remote string
+	// RemoteId is the id of the share on the remote server
+	RemoteId string
+	Token    string
+	// Name is the name of the shared file or folder on the remote server
+	Name string
+	// Owner is the user id of the owner on the remote server
+	Owner string
+	// User is the local user that received the share
+	User string
+	// MountPoint is the path of the share in the files of the user
+	MountPoint string
+	Accepted   bool
+
+	// Only filled for accepted shares
+	// ItemType is either "file" or "folder"
+	ItemType    string
+	MimeType    string
+	FileID      int64
+	Permissions Permissions
+	MTime       time.Time
+}
+
+// number decodes ids that some databases return as strings
+type number int64
+
+func (n *number) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "null" || s == "" {
+		*n = 0
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Invalid number %s", b)
+	}
+	*n = number(v)
+	return nil
+}
+
+type remoteShareData struct {
+	ID          number `json:"id"`
+	ShareType   number `json:"share_type"`
+	Remote      string `json:"remote"`
+	RemoteID    string `json:"remote_id"`
+	ShareToken  string `json:"share_token"`
+	Name        string `json:"name"`
+	Owner       string `json:"owner"`
+	User        string `json:"user"`
+	MountPoint  string `json:"mountpoint"`
+	Accepted    number `json:"accepted"`
+	Type        string `json:"type"`
+	MimeType    string `json:"mimetype"`
+	FileID      number `json:"file_id"`
+	Permissions number `json:"permissions"`
+	MTime       number `json:"mtime"`
+}
+
+// UnmarshalJSON decodes the share as sent by the remote shares API
+func (s *RemoteShare) UnmarshalJSON(b []byte) error {
+	var data remoteShareData
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	*s = RemoteShare{
+		Id:          int(data.ID),
+		Type:        int(data.ShareType),
+		Remote:      data.Remote,
+		RemoteId:    data.RemoteID,
+		Token:       data.ShareToken,
+		Name:        data.Name,
+		Owner:       data.Owner,
+		User:        data.User,
+		MountPoint:  data.MountPoint,
+		Accepted:    data.Accepted == 1,
+		ItemType:    data.Type,
+		MimeType:    data.MimeType,
+		FileID:      int64(data.FileID),
+		Permissions: Permissions(data.Permissions),
+	}
+	// The server calls folders "dir" here
+	if s.ItemType == "dir" {
+		s.ItemType = "folder"
+	}
+	if data.MTime != 0 {
+		s.MTime = time.Unix(int64(data.MTime), 0)
+	}
+	return nil
+}
+
+// GetRemoteShares returns the accepted shares of other servers
+func (sharing *Sharing) GetRemoteShares() ([]RemoteShare, error) {
+	return sharing.GetRemoteSharesContext(context.Background())
+}
+
+// GetRemoteSharesContext is like GetRemoteShares but aborts the request when ctx is done
+func (sharing *Sharing) GetRemoteSharesContext(ctx context.Context) ([]RemoteShare, error) {
+	return sharing.getRemoteShares(ctx, "/remote_shares", "listing the remote shares")
+}
+
+// GetPendingRemoteShares returns the shares of other servers that the user has not accepted yet
+func (sharing *Sharing) GetPendingRemoteShares() ([]RemoteShare, error) {
+	return sharing.GetPendingRemoteSharesContext(context.Background())
+}
+
+// GetPendingRemoteSharesContext is like GetPendingRemoteShares but aborts the request when ctx is done
+func (sharing *Sharing) GetPendingRemoteSharesContext(ctx context.Context) ([]RemoteShare, error) {
+	return sharing.getRemoteShares(ctx, "/remote_shares/pending", "listing the pending remote shares")
+}
+
+func (sharing *Sharing) getRemoteShares(ctx context.Context, path, op string) ([]RemoteShare, error) {
+	res, err := ocs.DoContext[[]RemoteShare](ctx, &sharing.ocs, http.MethodGet, endpoint+path+"?format=json", nil, true)
+	if err != nil {
+		return []RemoteShare{}, wrapError(op, err, nil)
+	}
+	if res.Data == nil {
+		return []RemoteShare{}, nil
+	}
+	return res.Data, nil
+}
+
+// AcceptRemoteShare accepts the pending share of another server and mounts it in the files of the user
+// Returns ErrShareNotFound when the share does not exist or was already accepted
+func (sharing *Sharing) AcceptRemoteShare(id int) error {
+	return sharing.AcceptRemoteShareContext(context.Background(), id)
+}
+
+// AcceptRemoteShareContext is like AcceptRemoteShare but aborts the request when ctx is done
+func (sharing *Sharing) AcceptRemoteShareContext(ctx context.Context, id int) error {
+	url := endpoint + "/remote_shares/pending/" + strconv.Itoa(id) + "?format=json"
+	_, err := ocs.DoContext[json.RawMessage](ctx, &sharing.ocs, http.MethodPost, url, nil, true)
+	return wrapError("accepting the remote share", err, shareErrors)
+}
+
+// DeclineRemoteShare declines the pending share of another server, the owner is notified
+// Returns ErrShareNotFound when the share does not exist or was already accepted
+func (sharing *Sharing) DeclineRemoteShare(id int) error {
+	return sharing.DeclineRemoteShareContext(context.Background(), id)
+}
+
+// DeclineRemoteShareContext is like DeclineRemoteShare but aborts the request when ctx is done
+func (sharing *Sharing) DeclineRemoteShareContext(ctx context.Context, id int) error {
+	url := endpoint + "/remote_shares/pending/" + strconv.Itoa(id) + "?format=json"
+	_, err := ocs.DoContext[json.RawMessage](ctx, &sharing.ocs, http.MethodDelete, url, nil, true)
+	return wrapError("declining the remote share", err, shareErrors)
+}
+
+// DeleteRemoteShare removes an accepted share of another server from the files of the user
+// Returns ErrShareNotFound when the share does not exist
+func (sharing *Sharing) DeleteRemoteShare(id int) error {
+	return sharing.DeleteRemoteShareContext(context.Background(), id)
+}
+
+// DeleteRemoteShareContext is like DeleteRemoteShare but aborts the request when ctx is done
+func (sharing *Sharing) DeleteRemoteShareContext(ctx context.Context, id int) error {
+	url := endpoint + "/remote_shares/" + strconv.Itoa(id) + "?format=json"
+	_, err := ocs.DoContext[json.RawMessage](ctx, &sharing.ocs, http.MethodDelete, url, nil, true)
+	return wrapError("deleting the remote share", err, shareErrors)
+}
+
+// RemoteShareDecision is what a RemoteSharePolicy does with a pending remote share
+type RemoteShareDecision int
+
+const (
+	// KeepPending leaves the share pending, e.g. for a person to decide
+	KeepPending RemoteShareDecision = iota
+	AcceptShare
+	DeclineShare
+)
+
+// RemoteSharePolicy decides what to do with a pending remote share
+type RemoteSharePolicy func(share RemoteShare) RemoteShareDecision
+
+// AcceptFrom returns a policy that accepts the shares of the given servers and keeps the others pending.
+// Servers are compared by host name, so "partner.example.com" matches "https://partner.example.com/".
+func AcceptFrom(servers ...string) RemoteSharePolicy {
+	hosts := map[string]bool{}
+	for _, server := range servers {
+		hosts[remoteHost(server)] = true
+	}
+	return func(share RemoteShare) RemoteShareDecision {
+		if hosts[remoteHost(share.Remote)] {
+			return AcceptShare
+		}
+		return KeepPending
+	}
+}
+
+// remoteHost returns the lower case host (and port) of a server URL with or without scheme
+func remoteHost(server string) string {
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+	u, err := url.Parse(server)
+	if err != nil {
+		return strings.ToLower(server)
+	}
+	return strings.ToLower(u.Host)
+}
+
+// ApplyRemoteSharePolicy accepts or declines the pending remote shares as the policy decides
+// and returns the handled shares. It stops at the first error, the shares handled until then are returned.
+func (sharing *Sharing) ApplyRemoteSharePolicy(policy RemoteSharePolicy) (accepted, declined []RemoteShare, err error) {
+	return sharing.ApplyRemoteSharePolicyContext(context.Background(), policy)
+}
+
+// ApplyRemoteSharePolicyContext is like ApplyRemoteSharePolicy but aborts the requests when ctx is done
+func (sharing *Sharing) ApplyRemoteSharePolicyContext(ctx context.Context, policy RemoteSharePolicy) (accepted, declined []RemoteShare, err error) {
+	accepted, declined = []RemoteShare{}, []RemoteShare{}
+	pending, err := sharing.GetPendingRemoteSharesContext(ctx)
+	if err != nil {
+		return accepted, declined, err
+	}
+
+	for _, share := range pending {
+		switch policy(share) {
+		case AcceptShare:
+			if err := sharing.AcceptRemoteShareContext(ctx, share.Id); err != nil {
+				return accepted, declined, err
+			}
+			accepted = append(accepted, share)
+		case DeclineShare:
+			if err := sharing.DeclineRemoteShareContext(ctx, share.Id); err != nil {
+				return accepted, declined, err
+			}
+			declined = append(declined, share)
+		}
+	}
+	return accepted, declined, nil
+}
@@ -0,0 +1,170 @@
+package sharing
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// addRemoteShare stores a pending share of another server with alice, ids are sent as strings like MySQL does
+func (s *fakeServer) addRemoteShare(remote, owner, name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := len(s.remoteShares) + 1
+	s.remoteShares[id] = map[string]interface{}{
+		"id":          strconv.Itoa(id),
+		"parent":      "-1",
+		"share_type":  "0",
+		"remote":      remote,
+		"remote_id":   strconv.Itoa(id + 500),
+		"share_token": "token" + strconv.Itoa(id),
+		"name":        "/" + name,
+		"owner":       owner,
+		"user":        "alice",
+		"mountpoint":  "{{TemporaryMountPointName#/" + name + "}}",
+		"accepted":    "0",
+	}
+	return id
+}
+
+// serveRemoteShares answers the remote shares API like the files_sharing app
+func (s *fakeServer) serveRemoteShares(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	p := strings.TrimPrefix(r.URL.Path, endpoint+"/remote_shares")
+	pending := strings.HasPrefix(p, "/pending")
+	id, _ := strconv.Atoi(strings.TrimPrefix(strings.TrimPrefix(p, "/pending"), "/"))
+
+	if p == "" || p == "/pending" {
+		list := []interface{}{}
+		for id := 1; id <= len(s.remoteShares); id++ {
+			if share := s.remoteShares[id]; share != nil && (share["accepted"] == "0") == pending {
+				list = append(list, share)
+			}
+		}
+		writeData(w, list)
+		return
+	}
+
+	share := s.remoteShares[id]
+	if share == nil || (share["accepted"] == "0") != pending {
+		writeFailure(w, 404, "wrong share ID, share does not exist.")
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		share["accepted"] = "1"
+		share["mountpoint"] = share["name"]
+		share["type"], share["mimetype"], share["file_id"], share["permissions"], share["mtime"] = "dir", "httpd/unix-directory", 900+id, 31, 1700000000
+	case http.MethodDelete:
+		s.remoteShares[id] = nil
+	}
+	writeData(w, []interface{}{})
+}
+
+func TestRemoteShareUnmarshal(t *testing.T) {
+	data := `{"id": 7, "parent": -1, "share_type": 1, "remote": "https://partner.example.com", "remote_id": "12",
+		"share_token": "Xyz", "password": "", "name": "/Contracts", "owner": "carol", "user": "alice",
+		"mountpoint": "/Contracts", "accepted": 1, "mimetype": "httpd/unix-directory", "mtime": 1700000000,
+		"permissions": 31, "type": "dir", "file_id": 345}`
+	var share RemoteShare
+	if err := json.Unmarshal([]byte(data), &share); err != nil {
+		t.Fatal(err)
+	}
+	expected := RemoteShare{
+		Id: 7, Type: TypeGroup, Remote: "https://partner.example.com", RemoteId: "12", Token: "Xyz",
+		Name: "/Contracts", Owner: "carol", User: "alice", MountPoint: "/Contracts", Accepted: true,
+		ItemType: "folder", MimeType: "httpd/unix-directory", FileID: 345, Permissions: PermissionAll,
+		MTime: time.Unix(1700000000, 0),
+	}
+	if !reflect.DeepEqual(share, expected) {
+		t.Errorf("Expected %+v\ngot %+v", expected, share)
+	}
+
+	if err := json.Unmarshal([]byte(`{"id": "seven"}`), &share); err == nil {
+		t.Error("Invalid ids should not be accepted")
+	}
+}
+
+func TestRemoteShares(t *testing.T) {
+	server, api := newFakeServer(t, "28.0.4")
+	contracts := server.addRemoteShare("https://partner.example.com", "carol", "Contracts")
+	spam := server.addRemoteShare("https://unknown.example.org", "mallory", "Invoice")
+
+	pending, err := api.GetPendingRemoteShares()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || pending[0].Id != contracts || pending[0].Owner != "carol" || pending[0].Accepted {
+		t.Errorf("Unexpected pending shares %+v", pending)
+	}
+
+	if err := api.AcceptRemoteShare(contracts); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.AcceptRemoteShare(contracts); !errors.Is(err, ErrShareNotFound) {
+		t.Errorf("Accepting twice should return ErrShareNotFound, got %v", err)
+	}
+	if err := api.DeclineRemoteShare(spam); err != nil {
+		t.Fatal(err)
+	}
+
+	accepted, err := api.GetRemoteShares()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accepted) != 1 || !accepted[0].Accepted || accepted[0].ItemType != "folder" || accepted[0].MountPoint != "/Contracts" {
+		t.Errorf("Unexpected remote shares %+v", accepted)
+	}
+	if pending, err := api.GetPendingRemoteShares(); err != nil || len(pending) != 0 {
+		t.Errorf("No share should be pending, got %v %v", pending, err)
+	}
+
+	if err := api.DeleteRemoteShare(contracts); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.DeleteRemoteShare(contracts); !errors.Is(err, ErrShareNotFound) {
+		t.Errorf("Expected ErrShareNotFound, got %v", err)
+	}
+}
+
+func TestApplyRemoteSharePolicy(t *testing.T) {
+	server, api := newFakeServer(t, "28.0.4")
+	partner := server.addRemoteShare("https://Partner.example.com/", "carol", "Contracts")
+	other := server.addRemoteShare("https://other.example.net", "dave", "Photos")
+	spam := server.addRemoteShare("https://unknown.example.org", "mallory", "Invoice")
+
+	accepted, declined, err := api.ApplyRemoteSharePolicy(AcceptFrom("partner.example.com", "https://friends.example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accepted) != 1 || accepted[0].Id != partner || len(declined) != 0 {
+		t.Errorf("Unexpected result %v %v", accepted, declined)
+	}
+
+	accepted, declined, err = api.ApplyRemoteSharePolicy(func(share RemoteShare) RemoteShareDecision {
+		if share.Owner == "mallory" {
+			return DeclineShare
+		}
+		return KeepPending
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accepted) != 0 || len(declined) != 1 || declined[0].Id != spam {
+		t.Errorf("Unexpected result %v %v", accepted, declined)
+	}
+
+	pending, err := api.GetPendingRemoteShares()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Id != other {
+		t.Errorf("Only the share of other.example.net should be pending, got %+v", pending)
+	}
+}
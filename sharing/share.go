@@ -0,0 +1,160 @@
+package sharing
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// expirationFormat is the format of expiration dates sent by the server, which has no time zone
+const expirationFormat = "2006-01-02 15:04:05"
+
+// Share is a file or folder shared by or with the logged in user
+type Share struct {
+	Id   int
+	Type int
+
+	Owner                string
+	OwnerDisplayName     string
+	Initiator            string
+	InitiatorDisplayName string
+
+	Path string
+	// ItemType is either "file" or "folder"
+	ItemType string
+	MimeType string
+	// FileSource is the id of the shared file or folder
+	FileSource int64
+	// FileTarget is the path of the share in the files of the recipient
+	FileTarget  string
+	Permissions Permissions
+	Time        time.Time
+	// Tags are the tags of the file, only returned when asked for with IncludeTags
+	Tags []string
+
+	// UserShare
+	// GroupShare
+	// FederatedShare
+	// MailShare
+	With            string
+	WithDisplayName string
+	// WithAvatar is the id of the avatar of the recipient, if the server sends one
+	WithAvatar string
+
+	// FederatedShare
+	// MailShare
+	// LinkShare
+	Token string
+
+	// LinkShare
+	// Expiration is the day the share expires. The server sends it without time zone,
+	// so it is midnight UTC of that day; compare the dates, not the instants.
+	Expiration time.Time
+	// Password is the hash of the password of link and mail shares
+	Password     string
+	Note         string
+	Label        string
+	HideDownload bool
+	// Attributes are additional settings of the share, they need Nextcloud 25
+	Attributes []ShareAttribute
+}
+
+// ShareAttribute is an additional setting of a share, e.g. whether downloads are allowed
+type ShareAttribute struct {
+	// Scope is the app or feature the attribute belongs to, e.g. "permissions"
+	Scope string `json:"scope"`
+	Key   string `json:"key"`
+	// Enabled is the value of the attribute, newer servers call it value
+	Enabled bool `json:"enabled"`
+}
+
+type shareData struct {
+	// The server sends the id as string, but the other ids as numbers
+	ID                   string   `json:"id"`
+	ShareType            int      `json:"share_type"`
+	UIDFileOwner         string   `json:"uid_file_owner"`
+	DisplayNameFileOwner string   `json:"displayname_file_owner"`
+	UIDOwner             string   `json:"uid_owner"`
+	DisplayNameOwner     string   `json:"displayname_owner"`
+	Path                 string   `json:"path"`
+	ItemType             string   `json:"item_type"`
+	MimeType             string   `json:"mimetype"`
+	FileSource           int64    `json:"file_source"`
+	FileTarget           string   `json:"file_target"`
+	Permissions          int      `json:"permissions"`
+	STime                int64    `json:"stime"`
+	Tags                 []string `json:"tags"`
+	ShareWith            string   `json:"share_with"`
+	ShareWithDisplayName string   `json:"share_with_displayname"`
+	ShareWithAvatar      string   `json:"share_with_avatar"`
+	Token                string   `json:"token"`
+	Expiration           string   `json:"expiration"`
+	Password             string   `json:"password"`
+	Note                 string   `json:"note"`
+	Label                string   `json:"label"`
+	HideDownload         int      `json:"hide_download"`
+	// Attributes is a JSON encoded list of attributes
+	Attributes string `json:"attributes"`
+}
+
+// UnmarshalJSON decodes the share as sent by the sharing API.
+// Fields the server sends as null are left empty.
+func (s *Share) UnmarshalJSON(b []byte) error {
+	var data shareData
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(data.ID)
+	if err != nil {
+		return fmt.Errorf("Invalid share id %q", data.ID)
+	}
+
+	*s = Share{
+		Id:                   id,
+		Type:                 data.ShareType,
+		Owner:                data.UIDFileOwner,
+		OwnerDisplayName:     data.DisplayNameFileOwner,
+		Initiator:            data.UIDOwner,
+		InitiatorDisplayName: data.DisplayNameOwner,
+		Path:                 data.Path,
+		ItemType:             data.ItemType,
+		MimeType:             data.MimeType,
+		FileSource:           data.FileSource,
+		FileTarget:           data.FileTarget,
+		Permissions:          Permissions(data.Permissions),
+		Time:                 time.Unix(data.STime, 0),
+		Tags:                 data.Tags,
+		With:                 data.ShareWith,
+		WithDisplayName:      data.ShareWithDisplayName,
+		WithAvatar:           data.ShareWithAvatar,
+		Token:                data.Token,
+		Password:             data.Password,
+		Note:                 data.Note,
+		Label:                data.Label,
+		HideDownload:         data.HideDownload == 1,
+	}
+
+	if data.Expiration != "" {
+		if s.Expiration, err = time.Parse(expirationFormat, data.Expiration); err != nil {
+			return fmt.Errorf("Invalid expiration date %q", data.Expiration)
+		}
+	}
+	if data.Attributes != "" {
+		var attributes []struct {
+			ShareAttribute
+			Value json.RawMessage `json:"value"`
+		}
+		if err := json.Unmarshal([]byte(data.Attributes), &attributes); err != nil {
+			return fmt.Errorf("Invalid share attributes: %w", err)
+		}
+		for _, a := range attributes {
+			if string(a.Value) == "true" || string(a.Value) == "false" {
+				a.Enabled = string(a.Value) == "true"
+			}
+			s.Attributes = append(s.Attributes, a.ShareAttribute)
+		}
+	}
+	return nil
+}
@@ -2,13 +2,16 @@
 package sharing
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
-	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/nextcloud/nextcloudgo"
+	"github.com/nextcloud/nextcloudgo/files"
 	"github.com/nextcloud/nextcloudgo/ocs"
 )
 
@@ -18,98 +21,268 @@ const TypeLink = 3
 const TypeMail = 4
 const TypeRemote = 6
 
-const PermissionRead = 1
-const PermissionUpdate = 2
-const PermissionCreate = 4
-const PermissionDelete = 8
-const PermissionShare = 16
-const PermissionAll = 31
-
 var (
-	endpoint = "ocs/v2.php/apps/files_sharing/api/v1"
+	endpoint = "/ocs/v2.php/apps/files_sharing/api/v1"
 )
 
-type Share struct {
-	Id   int
-	Type int
+type Sharing struct {
+	sdk   nextcloudgo.NextcloudGo
+	ocs   ocs.Request
+	files files.Files
+}
 
-	Owner                string
-	OwnerDisplayName     string
-	Initiator            string
-	InitiatorDisplayName string
+func New(sdk nextcloudgo.NextcloudGo) Sharing {
+	ocs := ocs.New(sdk)
+	return Sharing{sdk: sdk, ocs: ocs, files: files.New(sdk)}
+}
 
-	Path        string
-	Permissions int
-	Time        time.Time
+func (sharing *Sharing) GetShareById(id int) (Share, error) {
+	return sharing.GetShareByIdContext(context.Background(), id)
+}
 
-	// UserShare
-	// GroupShare
-	// FederatedShare
-	// MailShare
-	With            string
-	WithDisplayName string
+// GetShareByIdContext is like GetShareById but aborts the request when ctx is done
+func (sharing *Sharing) GetShareByIdContext(ctx context.Context, id int) (Share, error) {
+	url := endpoint + "/shares/" + strconv.Itoa(id)
 
-	// FederatedShare
-	// MailShare
-	// LinkShare
-	Token string
+	res, err := ocs.DoContext[[]Share](ctx, &sharing.ocs, http.MethodGet, url+"?format=json", nil, true)
+	if err != nil {
+		return Share{}, wrapError("getting the share", err, shareErrors)
+	}
 
-	// LinkShare
-	Expiration time.Time
+	if len(res.Data) == 0 {
+		return Share{}, errors.New("Share not found in response")
+	}
+	return res.Data[0], nil
 }
 
-type Sharing struct {
-	sdk nextcloudgo.NextcloudGo
-	ocs ocs.Request
+// ShareOptions are the optional settings of a new share, the zero value uses the defaults of the server
+type ShareOptions struct {
+	// Permissions is a combination of the Permission constants, 0 uses the default of the server
+	Permissions Permissions
+	// Password protects link and mail shares
+	Password string
+	// ExpireDate is the day the share expires, only the date is used
+	ExpireDate time.Time
+	// Note is shown to the recipient
+	Note string
+	// Label names link shares in the share list
+	Label string
+	// HideDownload hides the download button of link shares
+	HideDownload bool
+	// PublicUpload allows uploads to link shares of folders
+	PublicUpload bool
 }
 
-func New(sdk nextcloudgo.NextcloudGo) Sharing {
-	ocs := ocs.New(sdk)
-	return Sharing{sdk: sdk, ocs: ocs}
+// dateFormat is the format of expiration dates sent to the server
+const dateFormat = "2006-01-02"
+
+// CreateShare shares the file or folder at path with shareWith, which is a user id, group id,
+// email address or federated cloud id depending on shareType. It is ignored for TypeLink.
+// Returns ErrFileNotFound when the file does not exist and ErrInvalidInput when the server rejected the options.
+// HideDownload is set by a second request, when it fails the share is deleted again. Only when
+// that fails too, the created share is returned together with the error.
+func (sharing *Sharing) CreateShare(path string, shareType int, shareWith string, options ShareOptions) (Share, error) {
+	return sharing.CreateShareContext(context.Background(), path, shareType, shareWith, options)
 }
 
-func (sharing *Sharing) GetShareById(id int) (Share, error) {
-	url := endpoint + "/shares/" + strconv.Itoa(id)
+// CreateShareContext is like CreateShare but aborts the requests when ctx is done
+func (sharing *Sharing) CreateShareContext(ctx context.Context, path string, shareType int, shareWith string, options ShareOptions) (Share, error) {
+	if options.Permissions != 0 {
+		itemType := ""
+		// Only files and folders differ in create and delete, so the item is only looked up for them
+		if options.Permissions.Has(PermissionCreate) || options.Permissions.Has(PermissionDelete) {
+			var err error
+			if itemType, err = sharing.itemType(ctx, path); err != nil {
+				return Share{}, err
+			}
+		}
+		if err := options.Permissions.Validate(shareType, itemType); err != nil {
+			return Share{}, err
+		}
+	}
+
+	body := map[string]interface{}{"path": path, "shareType": shareType}
+	if shareType != TypeLink {
+		body["shareWith"] = shareWith
+	}
+	if options.Permissions != 0 {
+		body["permissions"] = options.Permissions
+	}
+	if options.Password != "" {
+		body["password"] = options.Password
+	}
+	if !options.ExpireDate.IsZero() {
+		body["expireDate"] = options.ExpireDate.Format(dateFormat)
+	}
+	if options.Note != "" {
+		body["note"] = options.Note
+	}
+	if options.Label != "" {
+		body["label"] = options.Label
+	}
+	if options.PublicUpload {
+		body["publicUpload"] = "true"
+	}
+	reader := new(bytes.Buffer)
+	json.NewEncoder(reader).Encode(body)
 
-	content, _, err := sharing.ocs.Request(http.MethodGet, url+"?format=json", true)
+	url := endpoint + "/shares?format=json"
+	res, err := ocs.DoContext[Share](ctx, &sharing.ocs, http.MethodPost, url, reader, true)
 	if err != nil {
-		return Share{}, err
+		return Share{}, wrapError("creating the share", err, pathErrors)
 	}
+	share := res.Data
 
-	if !ocs.ValidateStatusCode(content, 200) {
-		return Share{}, errors.New("Status code was invalid")
+	// The server only accepts hide-download when updating a share.
+	// When that fails, the share is deleted again so that retrying does not create a second link.
+	if options.HideDownload {
+		if err := sharing.UpdateShareHideDownloadContext(ctx, share.Id, true); err != nil {
+			if deleteErr := sharing.DeleteShareContext(context.WithoutCancel(ctx), share.Id); deleteErr != nil {
+				return share, err
+			}
+			return Share{}, err
+		}
+		share.HideDownload = true
 	}
+	return share, nil
+}
 
-	ocs := content["ocs"].(map[string]interface{})
-	data := ocs["data"].([]interface{})
-	share := data[0].(map[string]interface{})
-	return sharing.createShareFromMap(share)
+// itemType returns "file" or "folder" for the item at path
+func (sharing *Sharing) itemType(ctx context.Context, path string) (string, error) {
+	info, err := sharing.files.StatContext(ctx, path)
+	if errors.Is(err, files.ErrNotFound) {
+		return "", ErrFileNotFound
+	} else if err != nil {
+		return "", err
+	}
+	if info.IsDir {
+		return "folder", nil
+	}
+	return "file", nil
 }
 
-func (sharing *Sharing) createShareFromMap(share map[string]interface{}) (Share, error) {
-	log.Println(share)
-	s := Share{}
-	s.Id, _ = share["id"].(int)
-	s.Type, _ = share["share_type"].(int)
+// ShareField is the key of an editable share setting
+type ShareField string
+
+// Fields that can be changed with UpdateShare
+const (
+	FieldPermissions  ShareField = "permissions"
+	FieldPassword     ShareField = "password"
+	FieldExpireDate   ShareField = "expireDate"
+	FieldNote         ShareField = "note"
+	FieldLabel        ShareField = "label"
+	FieldHideDownload ShareField = "hideDownload"
+	FieldPublicUpload ShareField = "publicUpload"
+)
 
-	s.Owner = share["uid_file_owner"].(string)
-	s.OwnerDisplayName = share["displayname_file_owner"].(string)
-	s.Initiator = share["uid_owner"].(string)
-	s.InitiatorDisplayName = share["displayname_owner"].(string)
-	s.With = share["share_with"].(string)
-	s.WithDisplayName = share["share_with_displayname"].(string)
+// UpdateShare changes a single setting of the share, consider the typed helpers below
+// Returns ErrShareNotFound when the share does not exist and ErrInvalidInput when the value was rejected
+func (sharing *Sharing) UpdateShare(id int, field ShareField, value string) error {
+	return sharing.UpdateShareContext(context.Background(), id, field, value)
+}
 
-	s.Path = share["path"].(string)
-	if share["token"] != nil {
-		s.Token = share["token"].(string)
+// UpdateShareContext is like UpdateShare but aborts the request when ctx is done
+func (sharing *Sharing) UpdateShareContext(ctx context.Context, id int, field ShareField, value string) error {
+	body := map[string]string{string(field): value}
+	reader := new(bytes.Buffer)
+	json.NewEncoder(reader).Encode(body)
+
+	url := endpoint + "/shares/" + strconv.Itoa(id) + "?format=json"
+	_, err := ocs.DoContext[json.RawMessage](ctx, &sharing.ocs, http.MethodPut, url, reader, true)
+	return wrapError("updating the share", err, shareErrors)
+}
+
+// UpdateSharePermissions replaces the permissions of the share
+// Returns ErrInvalidPermissions when the permissions are not allowed for the share
+func (sharing *Sharing) UpdateSharePermissions(id int, permissions Permissions) error {
+	return sharing.UpdateSharePermissionsContext(context.Background(), id, permissions)
+}
+
+// UpdateSharePermissionsContext is like UpdateSharePermissions but aborts the requests when ctx is done
+func (sharing *Sharing) UpdateSharePermissionsContext(ctx context.Context, id int, permissions Permissions) error {
+	share, err := sharing.GetShareByIdContext(ctx, id)
+	if err != nil {
+		return err
 	}
-	s.Permissions, _ = share["permissions"].(int)
+	if err := permissions.Validate(share.Type, share.ItemType); err != nil {
+		return err
+	}
+	return sharing.UpdateShareContext(ctx, id, FieldPermissions, strconv.Itoa(int(permissions)))
+}
 
-	s.Time = time.Unix(int64(share["stime"].(float64)), 0)
-	if share["expiration"] != nil {
-		s.Expiration = time.Unix(int64(share["expiration"].(float64)), 0)
+// UpdateSharePassword sets the password of a link or mail share, an empty password removes it
+func (sharing *Sharing) UpdateSharePassword(id int, password string) error {
+	return sharing.UpdateSharePasswordContext(context.Background(), id, password)
+}
+
+// UpdateSharePasswordContext is like UpdateSharePassword but aborts the request when ctx is done
+func (sharing *Sharing) UpdateSharePasswordContext(ctx context.Context, id int, password string) error {
+	return sharing.UpdateShareContext(ctx, id, FieldPassword, password)
+}
+
+// UpdateShareExpireDate sets the day the share expires, the zero time removes the expiration
+func (sharing *Sharing) UpdateShareExpireDate(id int, expireDate time.Time) error {
+	return sharing.UpdateShareExpireDateContext(context.Background(), id, expireDate)
+}
+
+// UpdateShareExpireDateContext is like UpdateShareExpireDate but aborts the request when ctx is done
+func (sharing *Sharing) UpdateShareExpireDateContext(ctx context.Context, id int, expireDate time.Time) error {
+	value := ""
+	if !expireDate.IsZero() {
+		value = expireDate.Format(dateFormat)
 	}
+	return sharing.UpdateShareContext(ctx, id, FieldExpireDate, value)
+}
+
+// UpdateShareNote sets the note for the recipient
+func (sharing *Sharing) UpdateShareNote(id int, note string) error {
+	return sharing.UpdateShareNoteContext(context.Background(), id, note)
+}
+
+// UpdateShareNoteContext is like UpdateShareNote but aborts the request when ctx is done
+func (sharing *Sharing) UpdateShareNoteContext(ctx context.Context, id int, note string) error {
+	return sharing.UpdateShareContext(ctx, id, FieldNote, note)
+}
+
+// UpdateShareLabel sets the label of a link share
+func (sharing *Sharing) UpdateShareLabel(id int, label string) error {
+	return sharing.UpdateShareLabelContext(context.Background(), id, label)
+}
+
+// UpdateShareLabelContext is like UpdateShareLabel but aborts the request when ctx is done
+func (sharing *Sharing) UpdateShareLabelContext(ctx context.Context, id int, label string) error {
+	return sharing.UpdateShareContext(ctx, id, FieldLabel, label)
+}
+
+// UpdateShareHideDownload hides or shows the download button of a link share
+func (sharing *Sharing) UpdateShareHideDownload(id int, hide bool) error {
+	return sharing.UpdateShareHideDownloadContext(context.Background(), id, hide)
+}
+
+// UpdateShareHideDownloadContext is like UpdateShareHideDownload but aborts the request when ctx is done
+func (sharing *Sharing) UpdateShareHideDownloadContext(ctx context.Context, id int, hide bool) error {
+	return sharing.UpdateShareContext(ctx, id, FieldHideDownload, strconv.FormatBool(hide))
+}
+
+// UpdateSharePublicUpload allows or forbids uploads to a link share of a folder
+func (sharing *Sharing) UpdateSharePublicUpload(id int, allow bool) error {
+	return sharing.UpdateSharePublicUploadContext(context.Background(), id, allow)
+}
+
+// UpdateSharePublicUploadContext is like UpdateSharePublicUpload but aborts the request when ctx is done
+func (sharing *Sharing) UpdateSharePublicUploadContext(ctx context.Context, id int, allow bool) error {
+	return sharing.UpdateShareContext(ctx, id, FieldPublicUpload, strconv.FormatBool(allow))
+}
 
-	return s, nil
+// DeleteShare removes the share, the file itself is kept
+// Returns ErrShareNotFound when the share does not exist
+func (sharing *Sharing) DeleteShare(id int) error {
+	return sharing.DeleteShareContext(context.Background(), id)
+}
 
+// DeleteShareContext is like DeleteShare but aborts the request when ctx is done
+func (sharing *Sharing) DeleteShareContext(ctx context.Context, id int) error {
+	url := endpoint + "/shares/" + strconv.Itoa(id) + "?format=json"
+	_, err := ocs.DoContext[json.RawMessage](ctx, &sharing.ocs, http.MethodDelete, url, nil, true)
+	return wrapError("deleting the share", err, shareErrors)
 }
@@ -0,0 +1,425 @@
+package sharing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nextcloud/nextcloudgo"
+	"github.com/nextcloud/nextcloudgo/ocs"
+)
+
+// fakeServer keeps shares in memory like the files_sharing app
+type fakeServer struct {
+	*httptest.Server
+	version string
+
+	mu     sync.Mutex
+	shares map[int]map[string]interface{}
+	lastID int
+	// fileIDs are assigned to the paths on first use
+	fileIDs map[string]int
+	tags    map[string][]string
+	// updates holds the bodies of all PUT requests
+	updates []map[string]interface{}
+	// remoteShares are the shares of other servers with alice
+	remoteShares map[int]map[string]interface{}
+	// failUpdates rejects all PUT requests
+	failUpdates bool
+	// searches counts the DAV searches, lastQuery is the query of the last share listing
+	searches  int
+	lastQuery url.Values
+}
+
+func newFakeServer(t *testing.T, version string) (*fakeServer, Sharing) {
+	t.Helper()
+	s := &fakeServer{version: version, shares: map[int]map[string]interface{}{}, fileIDs: map[string]int{}, tags: map[string][]string{},
+		remoteShares: map[int]map[string]interface{}{}}
+	s.Server = httptest.NewServer(s)
+	t.Cleanup(s.Close)
+	nc, err := nextcloudgo.New(s.URL, "alice", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, New(nc)
+}
+
+func (s *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path == "/status.php" {
+		fmt.Fprintf(w, `{"installed":true,"maintenance":false,"version":"%s"}`, s.version)
+		return
+	}
+
+	if r.Method == "SEARCH" {
+		s.search(w, r)
+		return
+	}
+	if r.Method == "PROPFIND" && r.URL.Path == "/remote.php/dav/" {
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprint(w, `<d:multistatus xmlns:d="DAV:"><d:response><d:href>/remote.php/dav/</d:href><d:propstat><d:prop>`+
+			`<d:current-user-principal><d:href>/remote.php/dav/principals/users/alice/</d:href></d:current-user-principal>`+
+			`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response></d:multistatus>`)
+		return
+	}
+	if r.Method == "PROPFIND" {
+		s.propfind(w, strings.TrimPrefix(r.URL.Path, "/remote.php/dav/files/alice"))
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, endpoint+"/remote_shares") {
+		s.serveRemoteShares(w, r)
+		return
+	}
+
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+	w.Header().Set("Content-Type", "application/json")
+	id, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, endpoint+"/shares/"))
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == endpoint+"/shares":
+		s.list(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == endpoint+"/shares":
+		if body["path"] == "/missing" {
+			writeFailure(w, 404, "Wrong path, file/folder does not exist")
+			return
+		}
+		share := s.addShare(map[string]interface{}{
+			"share_type":             body["shareType"],
+			"uid_owner":              "alice",
+			"displayname_owner":      "Alice",
+			"uid_file_owner":         "alice",
+			"displayname_file_owner": "Alice",
+			"path":                   body["path"],
+			"permissions":            body["permissions"],
+			"share_with":             body["shareWith"],
+			"share_with_displayname": body["shareWith"],
+		})
+		// Link shares are sent with null recipients like the server does
+		if share["share_with"] == nil {
+			share["share_with_displayname"], share["token"] = "(Shared link)", "Abc123"
+		}
+		for _, key := range []string{"password", "expireDate", "note", "label", "publicUpload"} {
+			if value, ok := body[key]; ok {
+				share[key] = value
+			}
+		}
+		if date, ok := body["expireDate"].(string); ok {
+			share["expiration"] = date + " 00:00:00"
+		}
+		writeData(w, share)
+	case r.Method == http.MethodGet && r.URL.Path == endpoint+"/shares/pending":
+		s.listPending(w)
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, endpoint+"/shares/pending/"):
+		id, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, endpoint+"/shares/pending/"))
+		if s.shares[id] == nil || s.shares[id]["pending"] != true {
+			writeFailure(w, 404, "Wrong share ID, share does not exist")
+			return
+		}
+		delete(s.shares[id], "pending")
+		writeData(w, []interface{}{})
+	case s.shares[id] == nil:
+		writeFailure(w, 404, "Wrong share ID, share does not exist")
+	case r.Method == http.MethodGet:
+		writeData(w, []interface{}{s.shares[id]})
+	case r.Method == http.MethodPut:
+		if s.failUpdates {
+			writeFailure(w, 400, "Updating the share failed")
+			return
+		}
+		s.updates = append(s.updates, body)
+		writeData(w, s.shares[id])
+	case r.Method == http.MethodDelete:
+		delete(s.shares, id)
+		writeData(w, []interface{}{})
+	default:
+		writeFailure(w, 405, "Method not allowed")
+	}
+}
+
+// addShare stores the share with a new id and fills the fields that are the same for all test shares
+func (s *fakeServer) addShare(share map[string]interface{}) map[string]interface{} {
+	p := share["path"].(string)
+	if s.fileIDs[p] == 0 {
+		s.fileIDs[p] = len(s.fileIDs) + 100
+	}
+	s.lastID++
+	share["id"] = strconv.Itoa(s.lastID)
+	share["file_source"] = s.fileIDs[p]
+	share["item_type"] = itemType(p)
+	share["stime"] = 1700000000
+	share["token"] = nil
+	share["expiration"] = nil
+	s.shares[s.lastID] = share
+	return share
+}
+
+// list filters the shares of the logged in user like the files_sharing app
+func (s *fakeServer) list(w http.ResponseWriter, r *http.Request) {
+	user, _, _ := r.BasicAuth()
+	query := r.URL.Query()
+	s.lastQuery = query
+	if query.Get("path") == "/missing" {
+		writeFailure(w, 404, "Wrong path, file/folder does not exist")
+		return
+	}
+
+	ids := []int{}
+	for id := range s.shares {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	list := []interface{}{}
+	for _, id := range ids {
+		share := s.shares[id]
+		switch {
+		case share["pending"] == true:
+			continue
+		case query.Get("shared_with_me") == "true":
+			if share["share_with"] != user {
+				continue
+			}
+		case query.Get("reshares") == "true":
+			if share["uid_file_owner"] != user && share["uid_owner"] != user {
+				continue
+			}
+		case share["uid_owner"] != user:
+			continue
+		}
+
+		if p := query.Get("path"); p != "" && query.Get("subfiles") == "true" && path.Dir(share["path"].(string)) != p {
+			continue
+		} else if p != "" && query.Get("subfiles") != "true" && share["path"] != p {
+			continue
+		}
+
+		if query.Get("include_tags") == "true" {
+			tagged := map[string]interface{}{"tags": s.tags[share["path"].(string)]}
+			for key, value := range share {
+				tagged[key] = value
+			}
+			share = tagged
+		}
+		list = append(list, share)
+	}
+	writeData(w, list)
+}
+
+// itemType treats paths with an extension as files
+func itemType(p string) string {
+	if path.Ext(p) != "" {
+		return "file"
+	}
+	return "folder"
+}
+
+// propfind answers the stat of a file, every path except /missing exists
+func (s *fakeServer) propfind(w http.ResponseWriter, p string) {
+	if p == "/missing" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	resourceType := ""
+	if itemType(p) == "folder" {
+		resourceType = "<d:collection/>"
+	}
+	w.WriteHeader(http.StatusMultiStatus)
+	fmt.Fprintf(w, `<d:multistatus xmlns:d="DAV:"><d:response><d:href>/remote.php/dav/files/alice%s</d:href>`+
+		`<d:propstat><d:prop><d:resourcetype>%s</d:resourcetype></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>`+
+		`</d:response></d:multistatus>`, p, resourceType)
+}
+
+func writeData(w http.ResponseWriter, data interface{}) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ocs": map[string]interface{}{"meta": map[string]interface{}{"status": "ok", "statuscode": 200}, "data": data},
+	})
+}
+
+func writeFailure(w http.ResponseWriter, code int, message string) {
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ocs": map[string]interface{}{"meta": map[string]interface{}{"status": "failure", "statuscode": code, "message": message}, "data": []interface{}{}},
+	})
+}
+
+func TestCreateShare(t *testing.T) {
+	server, api := newFakeServer(t, "28.0.4")
+
+	share, err := api.CreateShare("/Projects/Physics", TypeGroup, "students", ShareOptions{Permissions: PermissionRead | PermissionCreate, Note: "Hand in by Friday"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if share.Id != 1 || share.With != "students" || share.Path != "/Projects/Physics" || share.Owner != "alice" {
+		t.Errorf("Unexpected share %+v", share)
+	}
+	if note := server.shares[1]["note"]; note != "Hand in by Friday" {
+		t.Errorf("Unexpected note %v", note)
+	}
+
+	expires := time.Date(2025, 2, 1, 15, 0, 0, 0, time.UTC)
+	link, err := api.CreateShare("/Projects/Physics", TypeLink, "ignored", ShareOptions{
+		Password: "s3cret!", ExpireDate: expires, Label: "Submissions", PublicUpload: true, HideDownload: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := server.shares[link.Id]
+	if stored["share_with"] != nil || stored["password"] != "s3cret!" || stored["expireDate"] != "2025-02-01" || stored["label"] != "Submissions" || stored["publicUpload"] != "true" {
+		t.Errorf("Unexpected link share %v", stored)
+	}
+	if !reflect.DeepEqual(server.updates, []map[string]interface{}{{"hideDownload": "true"}}) {
+		t.Errorf("Hide download should be set by an update, got %v", server.updates)
+	}
+	if !link.Expiration.Equal(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)) || link.Token != "Abc123" || link.With != "" || !link.HideDownload {
+		t.Errorf("Unexpected link share %+v", link)
+	}
+
+	server.failUpdates = true
+	if _, err := api.CreateShare("/Projects/Physics", TypeLink, "", ShareOptions{HideDownload: true}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Expected ErrInvalidInput, got %v", err)
+	}
+	if len(server.shares) != 2 {
+		t.Error("The share should be deleted when hiding the download fails")
+	}
+
+	_, err = api.CreateShare("/missing", TypeUser, "bob", ShareOptions{})
+	var ocsErr *ocs.Error
+	if !errors.Is(err, ErrFileNotFound) || !errors.As(err, &ocsErr) || ocsErr.StatusCode != 404 {
+		t.Errorf("Expected ErrFileNotFound, got %v", err)
+	}
+}
+
+func TestUpdateAndDeleteShare(t *testing.T) {
+	server, api := newFakeServer(t, "28.0.4")
+	share, err := api.CreateShare("/Report.pdf", TypeMail, "bob@example.com", ShareOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expires := time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)
+	for _, update := range []func() error{
+		func() error { return api.UpdateSharePermissions(share.Id, PermissionRead) },
+		func() error { return api.UpdateSharePassword(share.Id, "correct horse") },
+		func() error { return api.UpdateShareExpireDate(share.Id, expires) },
+		func() error { return api.UpdateShareExpireDate(share.Id, time.Time{}) },
+		func() error { return api.UpdateShareNote(share.Id, "FYI") },
+		func() error { return api.UpdateShareLabel(share.Id, "Bob") },
+		func() error { return api.UpdateSharePublicUpload(share.Id, false) },
+	} {
+		if err := update(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []map[string]interface{}{
+		{"permissions": "1"},
+		{"password": "correct horse"},
+		{"expireDate": "2025-06-30"},
+		{"expireDate": ""},
+		{"note": "FYI"},
+		{"label": "Bob"},
+		{"publicUpload": "false"},
+	}
+	if !reflect.DeepEqual(server.updates, expected) {
+		t.Errorf("Unexpected updates %v", server.updates)
+	}
+
+	if err := api.DeleteShare(share.Id); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.DeleteShare(share.Id); !errors.Is(err, ErrShareNotFound) {
+		t.Errorf("Expected ErrShareNotFound, got %v", err)
+	}
+	if err := api.UpdateShareNote(share.Id, "gone"); !errors.Is(err, ErrShareNotFound) {
+		t.Errorf("Expected ErrShareNotFound, got %v", err)
+	} else if err.Error() != "Share does not exist" {
+		t.Error(err.Error())
+	}
+	if _, err := api.GetShareById(share.Id); !errors.Is(err, ErrShareNotFound) {
+		t.Errorf("Expected ErrShareNotFound, got %v", err)
+	}
+}
+
+func TestShareUnmarshal(t *testing.T) {
+	// A link share as returned by Nextcloud 28
+	data := `{
+		"id": "42", "share_type": 3, "uid_owner": "alice", "displayname_owner": "Alice",
+		"permissions": 17, "can_edit": true, "can_delete": true, "stime": 1700000000, "parent": null,
+		"expiration": "2025-02-01 00:00:00", "token": "Abc123", "uid_file_owner": "bob", "displayname_file_owner": "Bob",
+		"note": "For the jury", "label": "Submissions", "path": "/Projects/Physics", "item_type": "folder",
+		"mimetype": "httpd/unix-directory", "has_preview": false, "storage_id": "home::bob", "storage": 2,
+		"item_source": 321, "file_source": 321, "file_parent": 12, "file_target": "/Physics", "item_size": 1024,
+		"item_mtime": 1700000000, "share_with": null, "share_with_displayname": "(Shared link)",
+		"password": "$2y$10$hash", "send_password_by_talk": false, "url": "https://cloud.example.com/s/Abc123",
+		"mail_send": 0, "hide_download": 1,
+		"attributes": "[{\"scope\":\"permissions\",\"key\":\"download\",\"enabled\":false},{\"scope\":\"permissions\",\"key\":\"edit\",\"value\":true}]"
+	}`
+	var share Share
+	if err := json.Unmarshal([]byte(data), &share); err != nil {
+		t.Fatal(err)
+	}
+	expected := Share{
+		Id:                   42,
+		Type:                 TypeLink,
+		Owner:                "bob",
+		OwnerDisplayName:     "Bob",
+		Initiator:            "alice",
+		InitiatorDisplayName: "Alice",
+		Path:                 "/Projects/Physics",
+		ItemType:             "folder",
+		MimeType:             "httpd/unix-directory",
+		FileSource:           321,
+		FileTarget:           "/Physics",
+		Permissions:          17,
+		Time:                 time.Unix(1700000000, 0),
+		WithDisplayName:      "(Shared link)",
+		Token:                "Abc123",
+		Expiration:           time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+		Password:             "$2y$10$hash",
+		Note:                 "For the jury",
+		Label:                "Submissions",
+		HideDownload:         true,
+		Attributes:           []ShareAttribute{{Scope: "permissions", Key: "download"}, {Scope: "permissions", Key: "edit", Enabled: true}},
+	}
+	if !reflect.DeepEqual(share, expected) {
+		t.Errorf("Expected %+v\ngot %+v", expected, share)
+	}
+
+	for _, invalid := range []string{
+		`{"id": 42}`,
+		`{"id": "42", "permissions": "all"}`,
+		`{"id": "42", "expiration": "tomorrow"}`,
+		`{"id": "42", "attributes": "not json"}`,
+	} {
+		if err := json.Unmarshal([]byte(invalid), &share); err == nil {
+			t.Errorf("%s should not be accepted", invalid)
+		}
+	}
+}
+
+func TestGetShareByIdEmptyData(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		writeData(w, []interface{}{})
+	}))
+	defer ts.Close()
+
+	api := New(nextcloudgo.NextcloudGo{ServerURL: ts.URL, User: "alice", Password: "secret"})
+	if _, err := api.GetShareById(1); err == nil {
+		t.Error("An empty response should be an error")
+	}
+}
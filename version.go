@@ -0,0 +1,248 @@
+package nextcloudgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnsupportedByServer is returned when a feature is not available in the version of the server
+var ErrUnsupportedByServer = errors.New("Not supported by the server")
+
+// Version is a parsed server version like 27.1.3.2
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+	Build int
+}
+
+// ParseVersion parses the numeric version of the server as reported by status.php,
+// e.g. "13.0.0.6". Missing parts are 0 and a suffix like " Beta 1" is ignored.
+func ParseVersion(version string) (Version, error) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	version, _, _ = strings.Cut(version, " ")
+	version, _, _ = strings.Cut(version, "-")
+
+	parts := strings.Split(version, ".")
+	if len(parts) > 4 {
+		return Version{}, fmt.Errorf("Invalid version %q", version)
+	}
+
+	var numbers [4]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("Invalid version %q", version)
+		}
+		numbers[i] = n
+	}
+	return Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2], Build: numbers[3]}, nil
+}
+
+// Compare returns -1 when v is older than other, 0 when they are equal and 1 when v is newer
+func (v Version) Compare(other Version) int {
+	a := [4]int{v.Major, v.Minor, v.Patch, v.Build}
+	b := [4]int{other.Major, other.Minor, other.Patch, other.Build}
+	for i := range a {
+		if a[i] < b[i] {
+			return -1
+		}
+		if a[i] > b[i] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// AtLeast returns true when v is the same or newer than other
+func (v Version) AtLeast(other Version) bool {
+	return v.Compare(other) >= 0
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d.%d", v.Major, v.Minor, v.Patch, v.Build)
+}
+
+// Feature is an API that is only available on some server versions
+type Feature string
+
+// Features that differ between the supported server versions
+const (
+	FeatureGroupDisplayName Feature = "group-displayname"
+	FeatureChunkingV2       Feature = "chunking-v2"
+	FeaturePendingShares    Feature = "pending-shares"
+)
+
+// featureVersions holds the first server version providing a feature
+var featureVersions = map[Feature]Version{
+	FeaturePendingShares:    {Major: 20},
+	FeatureGroupDisplayName: {Major: 21},
+	FeatureChunkingV2:       {Major: 26},
+}
+
+// MinimumVersion returns the first server version that provides the feature
+func (f Feature) MinimumVersion() (Version, bool) {
+	v, ok := featureVersions[f]
+	return v, ok
+}
+
+const (
+	// versionTTL is how long a requested version is used, so upgrades of the server are noticed
+	versionTTL = time.Hour
+	// failureTTL is how long a failed lookup is reported before the server is asked again
+	failureTTL = time.Minute
+)
+
+// serverInfo caches the version of the server between requests
+type serverInfo struct {
+	mu      sync.Mutex
+	version *Version
+	err     error
+	expires time.Time
+	// fixed is set for the version given to New, which never expires
+	fixed bool
+}
+
+// serverInfo returns the cache of the client and creates it for struct literals
+func (nc *NextcloudGo) serverInfo() *serverInfo {
+	lazyMu.Lock()
+	defer lazyMu.Unlock()
+	if nc.info == nil {
+		nc.info = &serverInfo{}
+	}
+	return nc.info
+}
+
+// ServerVersion returns the parsed version of the server.
+// It is read from status.php, or from the capabilities when status.php is not reachable.
+// The version is cached by the client for an hour and a failed lookup for a minute.
+func (nc *NextcloudGo) ServerVersion() (Version, error) {
+	return nc.ServerVersionContext(context.Background())
+}
+
+// ServerVersionContext is like ServerVersion but aborts the requests when ctx is done
+func (nc *NextcloudGo) ServerVersionContext(ctx context.Context) (Version, error) {
+	info := nc.serverInfo()
+	info.mu.Lock()
+	if info.fixed || (info.version != nil || info.err != nil) && time.Now().Before(info.expires) {
+		defer info.mu.Unlock()
+		if info.version == nil {
+			return Version{}, info.err
+		}
+		return *info.version, nil
+	}
+	info.mu.Unlock()
+
+	version, err := nc.statusVersion(ctx)
+	if err != nil {
+		var capabilitiesErr error
+		if version, capabilitiesErr = nc.capabilitiesVersion(ctx); capabilitiesErr == nil {
+			err = nil
+		}
+	}
+	if err != nil && ctx.Err() != nil {
+		// The lookup was aborted by the caller and says nothing about the server
+		return Version{}, err
+	}
+
+	info.mu.Lock()
+	defer info.mu.Unlock()
+	if err != nil {
+		info.expires = time.Now().Add(failureTTL)
+		if info.version != nil {
+			// Keep the version that expired, the server was most likely not upgraded in between
+			return *info.version, nil
+		}
+		info.err = err
+		return Version{}, err
+	}
+	info.version, info.err = &version, nil
+	info.expires = time.Now().Add(versionTTL)
+	return version, nil
+}
+
+func (nc *NextcloudGo) statusVersion(ctx context.Context) (Version, error) {
+	status, err := nc.StatusContext(ctx)
+	if err != nil {
+		return Version{}, err
+	}
+	return ParseVersion(status.Version)
+}
+
+// capabilitiesVersion reads the version block of the capabilities, which needs a login
+func (nc *NextcloudGo) capabilitiesVersion(ctx context.Context) (Version, error) {
+	response, err := nc.RequestContext(ctx, http.MethodGet, "/ocs/v2.php/cloud/capabilities?format=json", nil, true)
+	if err != nil {
+		return Version{}, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return Version{}, fmt.Errorf("Unexpected status %d of the capabilities", response.StatusCode)
+	}
+
+	var body struct {
+		OCS struct {
+			Data struct {
+				Version struct {
+					Major int `json:"major"`
+					Minor int `json:"minor"`
+					Micro int `json:"micro"`
+				} `json:"version"`
+			} `json:"data"`
+		} `json:"ocs"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return Version{}, err
+	}
+	v := body.OCS.Data.Version
+	if v.Major == 0 {
+		return Version{}, errors.New("No version in the capabilities")
+	}
+	return Version{Major: v.Major, Minor: v.Minor, Patch: v.Micro}, nil
+}
+
+// Supports returns true when the server provides the given feature
+func (nc *NextcloudGo) Supports(feature Feature) (bool, error) {
+	return nc.SupportsContext(context.Background(), feature)
+}
+
+// SupportsContext is like Supports but aborts the request when ctx is done
+func (nc *NextcloudGo) SupportsContext(ctx context.Context, feature Feature) (bool, error) {
+	minimum, ok := feature.MinimumVersion()
+	if !ok {
+		return false, fmt.Errorf("Unknown feature %q", feature)
+	}
+
+	version, err := nc.ServerVersionContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	return version.AtLeast(minimum), nil
+}
+
+// Require returns an error wrapping ErrUnsupportedByServer when the server
+// does not provide the given feature, so requests that would fail are not sent
+func (nc *NextcloudGo) Require(feature Feature) error {
+	return nc.RequireContext(context.Background(), feature)
+}
+
+// RequireContext is like Require but aborts the request when ctx is done.
+// When the version can not be determined, the error of the lookup is returned.
+func (nc *NextcloudGo) RequireContext(ctx context.Context, feature Feature) error {
+	ok, err := nc.SupportsContext(ctx, feature)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		minimum, _ := feature.MinimumVersion()
+		return fmt.Errorf("%w: %s requires Nextcloud %d", ErrUnsupportedByServer, feature, minimum.Major)
+	}
+	return nil
+}